@@ -0,0 +1,189 @@
+package walrus_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrAttributesUnsupported is returned when attributes are to be set but no AttributeSetter is configured
+var ErrAttributesUnsupported = errors.New("setting blob attributes is not configured")
+
+// defaultContentType is the content type aggregators serve blobs without a content-type attribute with
+const defaultContentType = "application/octet-stream"
+
+// Attributes are key-value pairs attached to a blob object on Sui. Aggregators return the
+// attributes they allow as response headers, so attributes such as content-type and
+// content-disposition tell other consumers how to serve the content.
+type Attributes map[string]string
+
+// FileAttributes returns the attributes that make aggregators serve a blob as a file with the
+// given name and content type. Empty arguments are left out.
+func FileAttributes(filename, contentType string) Attributes {
+	attributes := Attributes{}
+	if contentType != "" {
+		attributes["content-type"] = contentType
+	}
+	if filename != "" {
+		attributes["content-disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	}
+	return attributes
+}
+
+// Get returns the value of the attribute key, matched case-insensitively
+func (a Attributes) Get(key string) string {
+	if value, ok := a[key]; ok {
+		return value
+	}
+	for k, value := range a {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// ContentType returns the content-type attribute
+func (a Attributes) ContentType() string {
+	return a.Get("content-type")
+}
+
+// Filename returns the filename of the content-disposition attribute
+func (a Attributes) Filename() string {
+	_, params, err := mime.ParseMediaType(a.Get("content-disposition"))
+	if err != nil {
+		return ""
+	}
+	return params["filename"]
+}
+
+// AttributeSetter sets attributes on blob objects. Attributes live on the Sui blob object, so
+// setting them takes a transaction signed by the object's owner, which the publisher HTTP API
+// does not offer. Plug in an implementation backed by the owner's wallet, for example one that
+// runs `walrus set-blob-attribute`, with WithAttributeSetter. walrustest.Server implements it
+// for the fake.
+type AttributeSetter interface {
+	// SetBlobAttributes adds attributes to the blob object with the given Sui object ID,
+	// replacing the values of existing keys
+	SetBlobAttributes(ctx context.Context, objectID string, attributes map[string]string) error
+}
+
+// WithAttributeSetter lets the client set attributes on stored blobs, with StoreOptions.Attributes
+// or SetBlobAttributes
+func WithAttributeSetter(setter AttributeSetter) ClientOption {
+	return func(c *Client) {
+		c.attrSetter = setter
+	}
+}
+
+// SetBlobAttributes adds attributes to the blob object with the given Sui object ID. It returns
+// ErrAttributesUnsupported if no AttributeSetter is configured.
+func (c *Client) SetBlobAttributes(objectID string, attributes Attributes) error {
+	return c.SetBlobAttributesWithContext(context.Background(), objectID, attributes)
+}
+
+// SetBlobAttributesWithContext is like SetBlobAttributes but passes ctx to the AttributeSetter
+func (c *Client) SetBlobAttributesWithContext(ctx context.Context, objectID string, attributes Attributes) error {
+	if c.attrSetter == nil {
+		return ErrAttributesUnsupported
+	}
+	if err := c.attrSetter.SetBlobAttributes(ctx, objectID, attributes); err != nil {
+		return fmt.Errorf("failed to set attributes of blob object %s: %w", objectID, err)
+	}
+	return nil
+}
+
+// ReadWithMetadata is like Read but also returns the blob's metadata, including the blob
+// attributes the aggregator returned
+func (c *Client) ReadWithMetadata(blobID string, opts *ReadOptions) ([]byte, *BlobMetadata, error) {
+	return c.ReadWithMetadataWithContext(context.Background(), blobID, opts)
+}
+
+// ReadWithMetadataWithContext is like ReadWithMetadata but carries ctx through the request and the retry loop
+func (c *Client) ReadWithMetadataWithContext(ctx context.Context, blobID string, opts *ReadOptions) ([]byte, *BlobMetadata, error) {
+	return c.readContent(ctx, fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID)), opts)
+}
+
+// checkAttributes fails early if opts has attributes that can't be set
+func (c *Client) checkAttributes(opts *StoreOptions) error {
+	if opts != nil && len(opts.Attributes) > 0 && c.attrSetter == nil {
+		return ErrAttributesUnsupported
+	}
+	return nil
+}
+
+// applyAttributes sets the attributes of opts on the blob object created by a store. The blob
+// is stored either way, so resp is returned even if the attributes could not be set.
+func (c *Client) applyAttributes(ctx context.Context, resp *StoreResponse, opts *StoreOptions) (*StoreResponse, error) {
+	if opts == nil || len(opts.Attributes) == 0 {
+		return resp, nil
+	}
+	if resp.NewlyCreated == nil {
+		return resp, fmt.Errorf("cannot set attributes: blob %s was already certified, so no blob object was created", resp.Blob.BlobID)
+	}
+	return resp, c.SetBlobAttributesWithContext(ctx, resp.NewlyCreated.BlobObject.ID, opts.Attributes)
+}
+
+// DefaultAttributeHeaders are the blob attributes a Walrus aggregator returns as response
+// headers unless it is configured otherwise. Since aggregators send a Content-Type of
+// application/octet-stream for blobs without a content-type attribute, that value is not
+// reported as an attribute; BlobMetadata.ContentType still holds it.
+var DefaultAttributeHeaders = []string{
+	"content-type",
+	"authorization",
+	"content-disposition",
+	"content-encoding",
+	"content-language",
+	"content-location",
+	"link",
+}
+
+// WithAttributeHeaders sets the response headers that are reported as blob attributes in
+// BlobMetadata.Attributes. Use it when the aggregators are configured with other allowed
+// headers than DefaultAttributeHeaders.
+func WithAttributeHeaders(headers []string) ClientOption {
+	return func(c *Client) {
+		c.attrHeaders = make([]string, len(headers))
+		for i, header := range headers {
+			c.attrHeaders[i] = strings.ToLower(header)
+		}
+	}
+}
+
+// blobMetadata returns the metadata of a blob read or HEAD response
+func (c *Client) blobMetadata(resp *http.Response) *BlobMetadata {
+	metadata := &BlobMetadata{
+		ContentLength: resp.ContentLength,
+		ContentType:   resp.Header.Get("Content-Type"),
+		LastModified:  resp.Header.Get("Last-Modified"),
+		ETag:          resp.Header.Get("ETag"),
+	}
+
+	headers := c.attrHeaders
+	if headers == nil {
+		headers = DefaultAttributeHeaders
+	}
+	for _, header := range headers {
+		value := resp.Header.Get(header)
+		if header == "content-type" && isDefaultContentType(value) {
+			continue
+		}
+		if value != "" {
+			if metadata.Attributes == nil {
+				metadata.Attributes = make(Attributes)
+			}
+			metadata.Attributes[header] = value
+		}
+	}
+	return metadata
+}
+
+// isDefaultContentType reports whether contentType is the one aggregators send by default
+func isDefaultContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == defaultContentType
+}
@@ -0,0 +1,95 @@
+package walrus_go
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/namihq/walrus-go/walrustest"
+)
+
+// TestStoreAttributes tests that attributes set on store are returned by reads
+func TestStoreAttributes(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(
+		WithAggregatorURLs([]string{server.URL}),
+		WithPublisherURLs([]string{server.URL}),
+		WithAttributeSetter(server),
+	)
+
+	data := []byte("%PDF-1.7 not really")
+	attributes := FileAttributes("report 2024.pdf", "application/pdf")
+	resp, err := client.Store(data, &StoreOptions{Attributes: attributes})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	metadata, err := client.Head(resp.Blob.BlobID)
+	if err != nil {
+		t.Fatalf("Head failed: %v", err)
+	}
+	if metadata.Attributes.ContentType() != "application/pdf" || metadata.Attributes.Filename() != "report 2024.pdf" {
+		t.Errorf("Unexpected attributes: %v", metadata.Attributes)
+	}
+
+	read, metadata, err := client.ReadWithMetadata(resp.Blob.BlobID, nil)
+	if err != nil || string(read) != string(data) {
+		t.Fatalf("ReadWithMetadata returned %q, %v", read, err)
+	}
+	if metadata.Attributes.Get("Content-Disposition") != attributes["content-disposition"] {
+		t.Errorf("Expected the content-disposition attribute, got %v", metadata.Attributes)
+	}
+
+	// Attributes are added to the existing ones
+	if err := client.SetBlobAttributes(resp.NewlyCreated.BlobObject.ID, Attributes{"content-language": "en"}); err != nil {
+		t.Fatalf("SetBlobAttributes failed: %v", err)
+	}
+	metadata, err = client.Head(resp.Blob.BlobID)
+	if err != nil || metadata.Attributes.Get("content-language") != "en" || metadata.Attributes.Filename() == "" {
+		t.Errorf("Unexpected attributes: %v, %v", metadata, err)
+	}
+
+	// Storing the blob again creates no blob object to set attributes on
+	resp, err = client.Store(data, &StoreOptions{Attributes: attributes})
+	if err == nil || resp == nil || resp.AlreadyCertified == nil {
+		t.Errorf("Expected an already certified response with an error, got %+v, %v", resp, err)
+	}
+}
+
+// TestDefaultContentTypeAttribute tests that the content type aggregators send by default is no attribute
+func TestDefaultContentTypeAttribute(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(WithAggregatorURLs([]string{server.URL}))
+	blobID := server.PutBlob([]byte("no attributes"), 1)
+
+	metadata, err := client.Head(blobID)
+	if err != nil {
+		t.Fatalf("Head failed: %v", err)
+	}
+	if metadata.ContentType != "application/octet-stream" || metadata.Attributes != nil {
+		t.Errorf("Expected the default content type and no attributes, got %q and %v", metadata.ContentType, metadata.Attributes)
+	}
+	if metadata.Attributes.ContentType() != "" {
+		t.Errorf("Expected no content-type attribute, got %q", metadata.Attributes.ContentType())
+	}
+}
+
+// TestStoreAttributesUnsupported tests that attributes without an AttributeSetter fail before uploading
+func TestStoreAttributesUnsupported(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithPublisherURLs([]string{server.URL}))
+
+	_, err := client.Store([]byte("data"), &StoreOptions{Attributes: Attributes{"content-type": "text/plain"}})
+	if !errors.Is(err, ErrAttributesUnsupported) {
+		t.Errorf("Expected ErrAttributesUnsupported, got %v", err)
+	}
+	if requests := server.Requests(); len(requests) != 0 {
+		t.Errorf("Expected no requests, got %d", len(requests))
+	}
+
+	if err := client.SetBlobAttributes("0x1", Attributes{"a": "b"}); !errors.Is(err, ErrAttributesUnsupported) {
+		t.Errorf("Expected ErrAttributesUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package walrus_go
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin is how long before its expiry a cached JWT is replaced, so that it does not
+// expire while a request is in flight
+const tokenExpiryMargin = 30 * time.Second
+
+// errNoRefresh is returned by EndpointAuth.Refresh for endpoints whose credentials can't be refreshed
+var errNoRefresh = errors.New("credentials cannot be refreshed")
+
+// AuthProvider adds credentials to the requests sent to the endpoints it is scoped to with
+// WithAuthProvider, for example to publishers that require a JWT. Requests to other servers,
+// such as the source of StoreFromURL, are never passed to it.
+type AuthProvider interface {
+	// Authenticate adds credentials to req, which is sent to the endpoint with the base URL
+	// endpoint as configured with WithAggregatorURLs or WithPublisherURLs
+	Authenticate(ctx context.Context, endpoint string, req *http.Request) error
+}
+
+// AuthRefresher is implemented by AuthProviders whose credentials can be renewed. When an
+// endpoint answers 401 Unauthorized, Refresh is called and the request is sent once more to the
+// same endpoint before the retry policy is consulted.
+type AuthRefresher interface {
+	Refresh(ctx context.Context, endpoint string) error
+}
+
+// WithAuthProvider authenticates the requests to the endpoints with the given base URLs with
+// provider. Without endpoints, the requests to the publishers are authenticated, except those
+// to the public publishers in DefaultTestnetPublishers.
+//
+// Credentials are only ever sent where they are scoped to, since the default endpoint lists
+// are run by third parties: a JWT for a private publisher must not reach public aggregators.
+// An EndpointAuth is scoped by its own keys, so it applies to aggregators and publishers alike.
+func WithAuthProvider(provider AuthProvider, endpoints ...string) ClientOption {
+	return func(c *Client) {
+		c.auth = provider
+		c.authURLs = endpoints
+	}
+}
+
+// WithHeaders adds headers to the requests to the endpoints with the given base URLs, or to
+// every request to aggregators and publishers, including health probes, without endpoints.
+// Don't pass credentials without endpoints; use WithAuthProvider for them. Headers the client
+// sets itself, such as Content-Type or those of an AuthProvider, take precedence.
+func WithHeaders(headers http.Header, endpoints ...string) ClientOption {
+	return func(c *Client) {
+		c.headers = make(http.Header, len(headers))
+		for key, values := range headers {
+			c.headers[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+		c.headerURLs = endpoints
+	}
+}
+
+// staticAuth sets a fixed header value
+type staticAuth struct {
+	header string
+	value  string
+}
+
+func (a staticAuth) Authenticate(ctx context.Context, endpoint string, req *http.Request) error {
+	req.Header.Set(a.header, a.value)
+	return nil
+}
+
+// BearerToken authenticates with a fixed bearer token, such as a long-lived JWT
+func BearerToken(token string) AuthProvider {
+	return staticAuth{header: "Authorization", value: "Bearer " + token}
+}
+
+// APIKey authenticates by sending key in the given header, e.g. X-API-Key
+func APIKey(header, key string) AuthProvider {
+	return staticAuth{header: header, value: key}
+}
+
+// TokenSource fetches a new bearer token, for example a JWT issued by an identity provider
+type TokenSource func(ctx context.Context) (string, error)
+
+// RefreshingToken authenticates with bearer tokens fetched from a TokenSource. A token is reused
+// until an endpoint rejects it or, for JWTs with an exp claim, until shortly before it expires.
+// It is safe for concurrent use.
+type RefreshingToken struct {
+	source TokenSource
+	now    func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewRefreshingToken returns a RefreshingToken that fetches its tokens from source
+func NewRefreshingToken(source TokenSource) *RefreshingToken {
+	return &RefreshingToken{source: source, now: time.Now}
+}
+
+// Authenticate sets the current token, fetching one first if there is none or it is about to expire
+func (t *RefreshingToken) Authenticate(ctx context.Context, endpoint string, req *http.Request) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token == "" || (!t.expiry.IsZero() && t.now().After(t.expiry.Add(-tokenExpiryMargin))) {
+		if err := t.fetch(ctx); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return nil
+}
+
+// Refresh replaces the current token with a new one from the source
+func (t *RefreshingToken) Refresh(ctx context.Context, endpoint string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fetch(ctx)
+}
+
+// fetch gets a new token from the source; t.mu must be held
+func (t *RefreshingToken) fetch(ctx context.Context) error {
+	token, err := t.source(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch token: %w", err)
+	}
+	if token == "" {
+		return fmt.Errorf("token source returned an empty token")
+	}
+	t.token = token
+	t.expiry = jwtExpiry(token)
+	return nil
+}
+
+// jwtExpiry returns the time of the exp claim of a JWT, or the zero time if token is not a JWT
+// or has no exp claim. The signature is not checked; that is up to the endpoints.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// EndpointAuth authenticates each endpoint with its own credentials, keyed by base URL as
+// configured with WithAggregatorURLs or WithPublisherURLs. Requests to endpoints without an
+// entry are sent without credentials.
+type EndpointAuth map[string]AuthProvider
+
+// Authenticate delegates to the provider of endpoint
+func (a EndpointAuth) Authenticate(ctx context.Context, endpoint string, req *http.Request) error {
+	if provider := a.provider(endpoint); provider != nil {
+		return provider.Authenticate(ctx, endpoint, req)
+	}
+	return nil
+}
+
+// Refresh delegates to the provider of endpoint if it is an AuthRefresher
+func (a EndpointAuth) Refresh(ctx context.Context, endpoint string) error {
+	if refresher, ok := a.provider(endpoint).(AuthRefresher); ok {
+		return refresher.Refresh(ctx, endpoint)
+	}
+	return errNoRefresh
+}
+
+// provider returns the provider of endpoint, ignoring trailing slashes
+func (a EndpointAuth) provider(endpoint string) AuthProvider {
+	if provider, ok := a[endpoint]; ok {
+		return provider
+	}
+	for key, provider := range a {
+		if sameEndpoint(key, endpoint) {
+			return provider
+		}
+	}
+	return nil
+}
+
+// sameEndpoint reports whether two base URLs are the same, ignoring trailing slashes
+func sameEndpoint(a, b string) bool {
+	return strings.TrimRight(a, "/") == strings.TrimRight(b, "/")
+}
+
+// containsEndpoint reports whether endpoints contains baseURL, ignoring trailing slashes
+func containsEndpoint(endpoints []string, baseURL string) bool {
+	for _, endpoint := range endpoints {
+		if sameEndpoint(endpoint, baseURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticates reports whether the AuthProvider is scoped to the endpoint baseURL
+func (c *Client) authenticates(baseURL string) bool {
+	if c.auth == nil {
+		return false
+	}
+	if _, ok := c.auth.(EndpointAuth); ok {
+		return true
+	}
+	if c.authURLs != nil {
+		return containsEndpoint(c.authURLs, baseURL)
+	}
+	return containsEndpoint(c.PublisherURL, baseURL) && !containsEndpoint(DefaultTestnetPublishers, baseURL)
+}
+
+// authError is returned by send when the AuthProvider fails. Such failures are not the
+// endpoint's fault, so they end the request instead of being retried.
+type authError struct {
+	endpoint string
+	err      error
+}
+
+func (e *authError) Error() string {
+	return fmt.Sprintf("failed to authenticate to %s: %v", e.endpoint, e.err)
+}
+
+func (e *authError) Unwrap() error {
+	return e.err
+}
+
+// authorize adds the custom headers and the credentials scoped to the endpoint baseURL to req
+func (c *Client) authorize(req *http.Request, baseURL string) error {
+	if c.headerURLs == nil || containsEndpoint(c.headerURLs, baseURL) {
+		for key, values := range c.headers {
+			if _, ok := req.Header[key]; !ok {
+				req.Header[key] = append([]string(nil), values...)
+			}
+		}
+	}
+	if !c.authenticates(baseURL) {
+		return nil
+	}
+	if err := c.auth.Authenticate(req.Context(), baseURL, req); err != nil {
+		return &authError{endpoint: baseURL, err: err}
+	}
+	return nil
+}
+
+// send sends req to the endpoint baseURL with the custom headers and credentials. If the
+// endpoint answers 401 Unauthorized and the AuthProvider scoped to it can refresh its
+// credentials, req is sent once more with the refreshed credentials. Failures of the AuthProvider are returned as
+// *authError.
+func (c *Client) send(req *http.Request, baseURL string) (*http.Response, error) {
+	if err := c.authorize(req, baseURL); err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	refresher, ok := c.auth.(AuthRefresher)
+	ok = ok && c.authenticates(baseURL)
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if !ok || (hasBody && req.GetBody == nil) {
+		return resp, nil
+	}
+	if err := refresher.Refresh(req.Context(), baseURL); err != nil {
+		if errors.Is(err, errNoRefresh) {
+			return resp, nil
+		}
+		resp.Body.Close()
+		return nil, &authError{endpoint: baseURL, err: err}
+	}
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if hasBody {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+	}
+	if err := c.authorize(retry, baseURL); err != nil {
+		return nil, err
+	}
+	return c.httpClient.Do(retry)
+}
@@ -0,0 +1,235 @@
+package walrus_go
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/namihq/walrus-go/walrustest"
+)
+
+// TestBearerTokenAndHeaders tests that credentials are only sent to the endpoints they are
+// scoped to, by default the publishers, and custom headers to every endpoint
+func TestBearerTokenAndHeaders(t *testing.T) {
+	publisher := walrustest.NewServer()
+	defer publisher.Close()
+	aggregator := walrustest.NewServer()
+	defer aggregator.Close()
+	blobID := aggregator.PutBlob([]byte("data"), 1)
+
+	client := NewClient(
+		WithAggregatorURLs([]string{aggregator.URL}),
+		WithPublisherURLs([]string{publisher.URL}),
+		WithAuthProvider(BearerToken("secret")),
+		WithHeaders(http.Header{"x-client-name": {"walrus-go-test"}}),
+	)
+	if _, err := client.Store([]byte("data"), nil); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := client.Read(blobID, nil); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	client.CheckEndpoints(context.Background())
+
+	for _, test := range []struct {
+		server *walrustest.Server
+		auth   string
+	}{
+		{publisher, "Bearer secret"},
+		{aggregator, ""},
+	} {
+		requests := test.server.Requests()
+		if len(requests) != 2 {
+			t.Fatalf("Expected 2 requests, got %d", len(requests))
+		}
+		for _, req := range requests {
+			if req.Header.Get("Authorization") != test.auth || req.Header.Get("X-Client-Name") != "walrus-go-test" {
+				t.Errorf("Unexpected headers for %s %s: %v", req.Method, req.Path, req.Header)
+			}
+		}
+	}
+
+	// The public publishers never get credentials by default
+	client = NewClient(WithAuthProvider(BearerToken("secret")))
+	req, _ := http.NewRequest(http.MethodGet, DefaultTestnetPublishers[0]+"/v1/api", nil)
+	if err := client.authorize(req, DefaultTestnetPublishers[0]); err != nil || req.Header.Get("Authorization") != "" {
+		t.Errorf("Expected no credentials for a public publisher, got %v, %v", req.Header, err)
+	}
+
+	// Listed endpoints replace the publishers
+	client = NewClient(
+		WithAggregatorURLs([]string{aggregator.URL}),
+		WithPublisherURLs([]string{publisher.URL}),
+		WithAuthProvider(APIKey("X-API-Key", "key"), aggregator.URL+"/"),
+		WithHeaders(http.Header{"X-Tenant": {"walrus"}}, publisher.URL),
+	)
+	if _, err := client.Store([]byte("data"), nil); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := client.Read(blobID, nil); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if req := publisher.Requests()[2]; req.Header.Get("X-API-Key") != "" || req.Header.Get("X-Tenant") != "walrus" {
+		t.Errorf("Unexpected publisher headers: %v", req.Header)
+	}
+	if req := aggregator.Requests()[2]; req.Header.Get("X-API-Key") != "key" || req.Header.Get("X-Tenant") != "" {
+		t.Errorf("Unexpected aggregator headers: %v", req.Header)
+	}
+}
+
+// TestRefreshTokenOnUnauthorized tests that a rejected token is refreshed and the request sent again
+func TestRefreshTokenOnUnauthorized(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	server.InjectFault(walrustest.Fault{StatusCode: http.StatusUnauthorized, Body: "token expired", Times: 1})
+
+	fetches := 0
+	source := func(ctx context.Context) (string, error) {
+		fetches++
+		return fmt.Sprintf("token-%d", fetches), nil
+	}
+	retries := 0
+	client := NewClient(
+		WithAggregatorURLs([]string{server.URL}),
+		WithPublisherURLs([]string{server.URL}),
+		WithAuthProvider(NewRefreshingToken(source)),
+		WithRetryHook(func(RetryEvent) { retries++ }),
+	)
+
+	data := []byte("uploaded twice")
+	resp, err := client.Store(data, nil)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := client.Read(resp.Blob.BlobID, nil); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	if fetches != 2 || retries != 0 {
+		t.Errorf("Expected 2 token fetches and no retries, got %d and %d", fetches, retries)
+	}
+	expected := []string{"Bearer token-1", "Bearer token-2", "Bearer token-2"}
+	requests := server.Requests()
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected %d requests, got %d", len(expected), len(requests))
+	}
+	for i, req := range requests {
+		if auth := req.Header.Get("Authorization"); auth != expected[i] {
+			t.Errorf("Request %d: expected %q, got %q", i, expected[i], auth)
+		}
+	}
+	if stored, ok := server.Blob(resp.Blob.BlobID); !ok || string(stored) != string(data) {
+		t.Errorf("Expected the replayed upload to be stored, got %q", stored)
+	}
+}
+
+// TestRefreshingTokenExpiry tests that JWTs are replaced shortly before they expire
+func TestRefreshingTokenExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	jwt := func(exp time.Time) string {
+		payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"walrus","exp":%d}`, exp.Unix())))
+		return "eyJhbGciOiJIUzI1NiJ9." + payload + ".c2lnbmF0dXJl"
+	}
+
+	for _, test := range []struct {
+		name    string
+		exp     time.Time
+		fetches int
+	}{
+		{"valid", now.Add(time.Hour), 1},
+		{"about to expire", now.Add(10 * time.Second), 3},
+	} {
+		fetches := 0
+		token := NewRefreshingToken(func(ctx context.Context) (string, error) {
+			fetches++
+			return jwt(test.exp), nil
+		})
+		token.now = func() time.Time { return now }
+
+		for i := 0; i < 3; i++ {
+			req, _ := http.NewRequest(http.MethodGet, "/v1/api", nil)
+			if err := token.Authenticate(context.Background(), "", req); err != nil {
+				t.Fatalf("%s: Authenticate failed: %v", test.name, err)
+			}
+		}
+		if fetches != test.fetches {
+			t.Errorf("%s: expected %d fetches, got %d", test.name, test.fetches, fetches)
+		}
+	}
+}
+
+// TestEndpointAuth tests per-endpoint credentials and that a 401 without refresh is not retried
+func TestEndpointAuth(t *testing.T) {
+	first := walrustest.NewServer()
+	defer first.Close()
+	second := walrustest.NewServer()
+	defer second.Close()
+	blobID := first.PutBlob([]byte("data"), 1)
+	second.PutBlob([]byte("data"), 1)
+	first.InjectFault(walrustest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1})
+
+	client := NewClient(
+		WithAggregatorURLs([]string{first.URL, second.URL}),
+		WithRetryConfig(2, time.Millisecond),
+		WithAuthProvider(EndpointAuth{
+			first.URL:        BearerToken("first"),
+			second.URL + "/": APIKey("X-API-Key", "second"),
+		}),
+	)
+	if _, err := client.Read(blobID, nil); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if requests := first.Requests(); len(requests) != 1 || requests[0].Header.Get("Authorization") != "Bearer first" {
+		t.Errorf("Unexpected requests to the first aggregator: %+v", requests)
+	}
+	if requests := second.Requests(); len(requests) != 1 || requests[0].Header.Get("X-API-Key") != "second" || requests[0].Header.Get("Authorization") != "" {
+		t.Errorf("Unexpected requests to the second aggregator: %+v", requests)
+	}
+
+	second.InjectFault(walrustest.Fault{StatusCode: http.StatusUnauthorized})
+	client = NewClient(WithAggregatorURLs([]string{second.URL}), WithAuthProvider(EndpointAuth{}))
+	if _, err := client.Read(blobID, nil); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if requests := second.Requests(); len(requests) != 2 {
+		t.Errorf("Expected a single unauthorized request, got %d", len(requests)-1)
+	}
+}
+
+// TestAuthProviderFailure tests that a failing token source ends the request without sending it
+func TestAuthProviderFailure(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	errIssuer := errors.New("issuer unavailable")
+
+	for _, hedged := range []bool{false, true} {
+		fetches := 0
+		options := []ClientOption{
+			WithAggregatorURLs([]string{server.URL, server.URL + "/", server.URL + "//"}),
+			WithAuthProvider(NewRefreshingToken(func(ctx context.Context) (string, error) {
+				fetches++
+				return "", errIssuer
+			}), server.URL),
+		}
+		if hedged {
+			options = append(options, WithHedgedReads(time.Second, 2))
+		}
+		client := NewClient(options...)
+
+		_, err := client.Read("blob", nil)
+		var multiErr *MultiError
+		if !errors.Is(err, errIssuer) || errors.As(err, &multiErr) {
+			t.Errorf("hedged=%v: expected the token source error alone, got %v", hedged, err)
+		}
+		if fetches != 1 {
+			t.Errorf("hedged=%v: expected a single token fetch, got %d", hedged, fetches)
+		}
+	}
+	if requests := server.Requests(); len(requests) != 0 {
+		t.Errorf("Expected no requests, got %d", len(requests))
+	}
+}
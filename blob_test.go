@@ -0,0 +1,138 @@
+package walrus_go
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/namihq/walrus-go/encryption"
+)
+
+// rangeRecordingServer serves content with Range support and records the requested ranges
+type rangeRecordingServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	ranges []string
+}
+
+func newRangeRecordingServer(content []byte) *rangeRecordingServer {
+	s := &rangeRecordingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			s.mu.Lock()
+			s.ranges = append(s.ranges, r.Header.Get("Range"))
+			s.mu.Unlock()
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	return s
+}
+
+// TestOpenBlob tests random access to an unencrypted blob
+func TestOpenBlob(t *testing.T) {
+	content := make([]byte, 100*1024)
+	rand.Read(content)
+	server := newRangeRecordingServer(content)
+	defer server.Close()
+
+	client := NewClient(WithAggregatorURLs([]string{server.URL}))
+	blob, err := client.OpenBlob("test-blob", nil)
+	if err != nil {
+		t.Fatalf("Failed to open blob: %v", err)
+	}
+	if blob.Size() != int64(len(content)) {
+		t.Fatalf("Expected size %d, got %d", len(content), blob.Size())
+	}
+
+	if _, err := blob.Seek(5000, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek: %v", err)
+	}
+	buf := make([]byte, 100)
+	if _, err := io.ReadFull(blob, buf); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if !bytes.Equal(buf, content[5000:5100]) {
+		t.Error("Read returned wrong content")
+	}
+
+	if len(server.ranges) != 1 || server.ranges[0] != "bytes=5000-5099" {
+		t.Errorf("Expected a single range request for bytes=5000-5099, got %v", server.ranges)
+	}
+
+	// Reading past the end returns io.EOF
+	n, err := blob.ReadAt(buf, int64(len(content))-10)
+	if n != 10 || err != io.EOF {
+		t.Errorf("Expected 10 bytes and io.EOF at the end of the blob, got %d bytes and %v", n, err)
+	}
+}
+
+// TestOpenEncryptedBlob tests random access to an encrypted blob
+func TestOpenEncryptedBlob(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	plaintext := make([]byte, 1024*1024)
+	rand.Read(plaintext)
+
+	cipher, _ := encryption.NewGCMContentCipher(key)
+	var encrypted bytes.Buffer
+	if err := cipher.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	server := newRangeRecordingServer(encrypted.Bytes())
+	defer server.Close()
+
+	client := NewClient(WithAggregatorURLs([]string{server.URL}))
+	readOpts := &ReadOptions{Encryption: &EncryptionOptions{Key: key}}
+
+	blob, err := client.OpenBlob("test-blob", readOpts)
+	if err != nil {
+		t.Fatalf("Failed to open blob: %v", err)
+	}
+	if blob.Size() != int64(len(plaintext)) {
+		t.Fatalf("Expected plaintext size %d, got %d", len(plaintext), blob.Size())
+	}
+
+	server.ranges = nil
+	buf := make([]byte, 1000)
+	if _, err := blob.ReadAt(buf, 500000); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if !bytes.Equal(buf, plaintext[500000:501000]) {
+		t.Error("ReadAt returned wrong content")
+	}
+
+	// The header was read when opening, so only the chunk covering the read is fetched
+	if len(server.ranges) != 1 {
+		t.Errorf("Expected 1 range request, got %v", server.ranges)
+	}
+
+	data, err := client.ReadRange("test-blob", 1024*1024-10, 100, readOpts)
+	if err != nil {
+		t.Fatalf("Failed to read encrypted range: %v", err)
+	}
+	if !bytes.Equal(data, plaintext[1024*1024-10:]) {
+		t.Error("ReadRange returned wrong content")
+	}
+}
+
+// TestOpenBlobUnsupportedCipher tests that ciphers without random access are rejected
+func TestOpenBlobUnsupportedCipher(t *testing.T) {
+	client := NewClient(WithAggregatorURLs([]string{"http://127.0.0.1:0"}))
+	_, err := client.OpenBlob("test-blob", &ReadOptions{
+		Encryption: &EncryptionOptions{
+			Key:   make([]byte, 32),
+			Suite: encryption.AES256CBC,
+			IV:    make([]byte, 16),
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not support random access") {
+		t.Errorf("Expected random access error, got: %v", err)
+	}
+}
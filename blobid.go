@@ -0,0 +1,95 @@
+package walrus_go
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BlobIDSize is the size of a decoded blob ID in bytes
+const BlobIDSize = 32
+
+var (
+	// ErrBlobIDUnsupported is returned by ComputeBlobID when no BlobIDComputer is configured
+	ErrBlobIDUnsupported = errors.New("blob ID computation is not configured")
+
+	// ErrBlobIDMismatch means the publisher returned a different blob ID than the one computed
+	// locally for the uploaded content
+	ErrBlobIDMismatch = errors.New("blob ID mismatch")
+)
+
+// BlobIDComputer derives the blob ID that Walrus assigns to content.
+//
+// On Walrus the blob ID is the hash of the blob's metadata: the RedStuff encoding type, the
+// unencoded length and the Merkle root over the hashes of all encoded slivers. It therefore
+// depends on the exact erasure code and on the number of shards of the network.
+//
+// The SDK does not ship a derivation of real Walrus blob IDs yet: it needs a byte-exact port of
+// the Reed-Solomon encoder and BLAKE2b hashing, verified against blobs stored on the network.
+// Until then, an implementation can be plugged in with WithBlobIDComputer.
+// walrustest.BlobIDComputer matches the fake server in the walrustest package only.
+type BlobIDComputer interface {
+	// ComputeBlobID returns the encoded blob ID of the size bytes read from r
+	ComputeBlobID(r io.Reader, size int64) (string, error)
+}
+
+// WithBlobIDComputer lets the client compute blob IDs locally. Plaintext uploads from data,
+// files and seekable readers are then verified against the blob ID returned by the publisher.
+func WithBlobIDComputer(computer BlobIDComputer) ClientOption {
+	return func(c *Client) {
+		c.blobIDs = computer
+	}
+}
+
+// ComputeBlobID returns the blob ID that data will be stored under, using the configured
+// BlobIDComputer. It returns ErrBlobIDUnsupported if none is configured.
+func (c *Client) ComputeBlobID(data []byte) (string, error) {
+	if c.blobIDs == nil {
+		return "", ErrBlobIDUnsupported
+	}
+	return c.blobIDs.ComputeBlobID(bytes.NewReader(data), int64(len(data)))
+}
+
+// EncodeBlobID encodes a raw blob ID in the unpadded URL-safe base64 form used by the API
+func EncodeBlobID(id [BlobIDSize]byte) string {
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+// DecodeBlobID decodes a blob ID in the unpadded URL-safe base64 form used by the API
+func DecodeBlobID(blobID string) ([BlobIDSize]byte, error) {
+	var id [BlobIDSize]byte
+	raw, err := base64.RawURLEncoding.DecodeString(blobID)
+	if err != nil {
+		return id, fmt.Errorf("invalid blob ID %q: %w", blobID, err)
+	}
+	if len(raw) != BlobIDSize {
+		return id, fmt.Errorf("invalid blob ID %q: expected %d bytes, got %d", blobID, BlobIDSize, len(raw))
+	}
+	copy(id[:], raw)
+	return id, nil
+}
+
+// precomputeBlobID computes the blob ID of an upload, or returns "" if the client has no
+// BlobIDComputer or the content cannot be read twice. The reader is left at its original position.
+func (c *Client) precomputeBlobID(reader io.Reader, size int64) (string, error) {
+	if c.blobIDs == nil {
+		return "", nil
+	}
+	open, remaining, ok := rewindableSource(reader)
+	if !ok {
+		return "", nil
+	}
+	if size < 0 || size > remaining {
+		size = remaining
+	}
+
+	blobID, err := c.blobIDs.ComputeBlobID(io.LimitReader(open(), size), size)
+	// Rewind sources that share their position with reader
+	open()
+	if err != nil {
+		return "", fmt.Errorf("failed to compute blob ID: %w", err)
+	}
+	return blobID, nil
+}
@@ -0,0 +1,105 @@
+package walrus_go
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/namihq/walrus-go/walrustest"
+)
+
+// TestBlobIDVerification tests that store responses are checked against the computed blob ID
+func TestBlobIDVerification(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+
+	client := NewClient(
+		WithAggregatorURLs([]string{server.URL}),
+		WithPublisherURLs([]string{server.URL}),
+		WithBlobIDComputer(walrustest.BlobIDComputer{}),
+	)
+
+	data := []byte("content with a known blob ID")
+	expected, err := client.ComputeBlobID(data)
+	if err != nil {
+		t.Fatalf("ComputeBlobID failed: %v", err)
+	}
+
+	resp, err := client.Store(data, nil)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if resp.Blob.BlobID != expected {
+		t.Errorf("Expected blob ID %s, got %s", expected, resp.Blob.BlobID)
+	}
+
+	// Files are rewound after computing the ID, so the full content is uploaded
+	path := filepath.Join(t.TempDir(), "blob.txt")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.StoreFile(path, nil); err != nil {
+		t.Fatalf("StoreFile failed: %v", err)
+	}
+	if _, err := client.StoreFromReaderWithLength(bytes.NewReader(append(data, "trailing"...)), int64(len(data)), nil); err != nil {
+		t.Fatalf("StoreFromReaderWithLength failed: %v", err)
+	}
+}
+
+// TestBlobIDMismatch tests that a publisher returning an unexpected blob ID is reported
+func TestBlobIDMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"newlyCreated":{"blobObject":{"blobId":"unexpected-id","storage":{"endEpoch":2}}}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithPublisherURLs([]string{server.URL}),
+		WithBlobIDComputer(walrustest.BlobIDComputer{}),
+	)
+
+	_, err := client.Store([]byte("data"), nil)
+	if !errors.Is(err, ErrBlobIDMismatch) {
+		t.Fatalf("Expected ErrBlobIDMismatch, got: %v", err)
+	}
+
+	// Encrypted uploads are not verified, since their blob ID can't be computed up front
+	key := bytes.Repeat([]byte{1}, 32)
+	if _, err := client.Store([]byte("data"), &StoreOptions{Encryption: &EncryptionOptions{Key: key}}); err != nil {
+		t.Errorf("Expected encrypted upload to skip verification, got: %v", err)
+	}
+}
+
+// TestComputeBlobIDUnsupported tests ComputeBlobID without a BlobIDComputer
+func TestComputeBlobIDUnsupported(t *testing.T) {
+	if _, err := NewClient().ComputeBlobID([]byte("data")); !errors.Is(err, ErrBlobIDUnsupported) {
+		t.Errorf("Expected ErrBlobIDUnsupported, got: %v", err)
+	}
+}
+
+// TestEncodeDecodeBlobID tests the blob ID text encoding
+func TestEncodeDecodeBlobID(t *testing.T) {
+	var raw [BlobIDSize]byte
+	for i := range raw {
+		raw[i] = byte(i * 7)
+	}
+
+	encoded := EncodeBlobID(raw)
+	if len(encoded) != 43 {
+		t.Errorf("Expected a 43 character blob ID, got %q", encoded)
+	}
+	decoded, err := DecodeBlobID(encoded)
+	if err != nil || decoded != raw {
+		t.Errorf("Round trip failed: %v", err)
+	}
+
+	for _, invalid := range []string{"", "not base64!", EncodeBlobID(raw) + "AA", encoded + "="} {
+		if _, err := DecodeBlobID(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
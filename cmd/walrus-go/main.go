@@ -0,0 +1,565 @@
+// Command walrus-go stores and reads blobs on Walrus from the command line.
+//
+// Usage:
+//
+//	walrus-go [global flags] <command> [flags] [arguments]
+//
+// Commands:
+//
+//	store       store a file, or standard input if the file is "-"
+//	store-url   download content from a URL and store it
+//	read        read a blob to standard output or a file
+//	head        print the metadata of a blob
+//	api-spec    print the API specification of an aggregator or publisher
+//	endpoints   print the health of the configured endpoints
+//
+// Results are written to standard output, progress messages to standard error.
+// Run "walrus-go <command> -h" for the flags of a command.
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	walrus "github.com/namihq/walrus-go"
+	"github.com/namihq/walrus-go/encryption"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli holds the state shared by all commands
+type cli struct {
+	client *walrus.Client
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	quiet  bool
+}
+
+// command is a subcommand; it returns a usageError for invalid arguments
+type command struct {
+	summary string
+	run     func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"store":     {summary: "store a file, or standard input if the file is \"-\"", run: runStore},
+	"store-url": {summary: "download content from a URL and store it", run: runStoreURL},
+	"read":      {summary: "read a blob to standard output or a file", run: runRead},
+	"head":      {summary: "print the metadata of a blob", run: runHead},
+	"api-spec":  {summary: "print the API specification of an aggregator or publisher", run: runAPISpec},
+	"endpoints": {summary: "print the health of the configured endpoints", run: runEndpoints},
+}
+
+// usageError reports invalid command line arguments
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// run executes the command line args and returns the exit code: 0 on success, 1 if the
+// command failed and 2 for invalid arguments
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("walrus-go", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	network := fs.String("network", "testnet", "network name (testnet, mainnet, local) or path to a JSON/YAML network file")
+	aggregators := fs.String("aggregator", "", "comma-separated aggregator URLs, overriding the network's")
+	publishers := fs.String("publisher", "", "comma-separated publisher URLs, overriding the network's")
+	timeout := fs.Duration("timeout", 0, "overall timeout for the command, e.g. 30s (default none)")
+	quiet := fs.Bool("quiet", false, "don't print progress messages")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: walrus-go [global flags] <command> [flags] [arguments]\n\nCommands:\n")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stderr, "  %-10s  %s\n", name, commands[name].summary)
+		}
+		fmt.Fprintf(stderr, "\nGlobal flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "walrus-go: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	opts, err := clientOptions(*network, *aggregators, *publishers)
+	if err != nil {
+		fmt.Fprintf(stderr, "walrus-go: %v\n", err)
+		return 2
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	c := &cli{
+		client: walrus.NewClient(opts...),
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		quiet:  *quiet,
+	}
+	if err := cmd.run(ctx, c, fs.Args()[1:]); err != nil {
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(stderr, "walrus-go %s: %v\n", fs.Arg(0), err)
+			return 2
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "walrus-go %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+// clientOptions resolves the global network flags into client options
+func clientOptions(network, aggregators, publishers string) ([]walrus.ClientOption, error) {
+	profile, ok := walrus.LookupNetwork(network)
+	if !ok {
+		loaded, err := walrus.LoadNetwork(network)
+		if err != nil {
+			return nil, fmt.Errorf("unknown network %q: %w", network, err)
+		}
+		profile = *loaded
+	}
+
+	opts := []walrus.ClientOption{walrus.WithNetwork(profile)}
+	if aggregators != "" {
+		opts = append(opts, walrus.WithAggregatorURLs(splitList(aggregators)))
+	}
+	if publishers != "" {
+		opts = append(opts, walrus.WithPublisherURLs(splitList(publishers)))
+	}
+	return opts, nil
+}
+
+// progress prints a progress message to standard error unless -quiet is set
+func (c *cli) progress(format string, args ...interface{}) {
+	if !c.quiet {
+		fmt.Fprintf(c.stderr, format+"\n", args...)
+	}
+}
+
+// progressInterval limits how often the progress of a transfer is printed
+const progressInterval = 200 * time.Millisecond
+
+// transferProgress returns a ProgressFunc that prints the progress of a transfer to standard
+// error, and a function that ends the progress line. With -quiet the ProgressFunc is nil.
+func (c *cli) transferProgress(label string) (walrus.ProgressFunc, func()) {
+	if c.quiet {
+		return nil, func() {}
+	}
+
+	var last time.Time
+	printed := false
+	report := func(transferred, total int64) {
+		now := time.Now()
+		if transferred != total && now.Sub(last) < progressInterval {
+			return
+		}
+		last = now
+		if total > 0 {
+			fmt.Fprintf(c.stderr, "\r%s: %s / %s (%d%%)", label, formatBytes(transferred), formatBytes(total), transferred*100/total)
+		} else {
+			fmt.Fprintf(c.stderr, "\r%s: %s", label, formatBytes(transferred))
+		}
+		printed = true
+	}
+	done := func() {
+		if printed {
+			fmt.Fprintln(c.stderr)
+			printed = false
+		}
+	}
+	return report, done
+}
+
+// formatBytes formats a byte count for humans
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// printJSON writes v to standard output as indented JSON
+func (c *cli) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// newFlagSet creates the flag set of a command
+func (c *cli) newFlagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: walrus-go %s [flags] %s\n\nFlags:\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses the flags of a command and checks that exactly n arguments remain
+func parseArgs(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{msg: err.Error()}
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return &usageError{msg: fmt.Sprintf("expected %d argument(s), got %d", n, fs.NArg())}
+	}
+	return nil
+}
+
+// storeFlags are the flags shared by store and store-url
+type storeFlags struct {
+	epochs       int
+	deletable    bool
+	sendObjectTo string
+	encryption   encryptionFlags
+}
+
+func (f *storeFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.epochs, "epochs", 0, "number of epochs to store the blob for (default from the network)")
+	fs.BoolVar(&f.deletable, "deletable", false, "store a deletable blob")
+	fs.StringVar(&f.sendObjectTo, "send-object-to", "", "address to send the blob object to")
+	f.encryption.register(fs)
+}
+
+func (f *storeFlags) options() (*walrus.StoreOptions, error) {
+	enc, err := f.encryption.options()
+	if err != nil {
+		return nil, err
+	}
+	return &walrus.StoreOptions{
+		Epochs:       f.epochs,
+		Deletable:    f.deletable,
+		SendObjectTo: f.sendObjectTo,
+		Encryption:   enc,
+	}, nil
+}
+
+// encryptionFlags configure client-side encryption
+type encryptionFlags struct {
+	keyFile  string
+	kekFiles stringList
+	cipher   string
+	iv       string
+}
+
+func (f *encryptionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.keyFile, "key-file", "", "file with the encryption key (hex, base64 or raw)")
+	fs.Var(&f.kekFiles, "kek-file", "file with a key-encryption key for envelope encryption; repeat for old keys, the first is current")
+	fs.StringVar(&f.cipher, "cipher", string(encryption.AES256GCM), "cipher suite: AES256GCM or AES256CBC")
+	fs.StringVar(&f.iv, "iv", "", "hex encoded IV, required for AES256CBC")
+}
+
+// options returns the encryption options, or nil if no key was given
+func (f *encryptionFlags) options() (*walrus.EncryptionOptions, error) {
+	if f.keyFile != "" && len(f.kekFiles) > 0 {
+		return nil, &usageError{msg: "-key-file and -kek-file are mutually exclusive"}
+	}
+
+	suite := encryption.CipherSuite(strings.ToUpper(f.cipher))
+	if !suite.IsValid() {
+		return nil, &usageError{msg: fmt.Sprintf("unsupported cipher suite: %s", f.cipher)}
+	}
+
+	var iv []byte
+	if f.iv != "" {
+		var err error
+		if iv, err = hex.DecodeString(f.iv); err != nil {
+			return nil, &usageError{msg: fmt.Sprintf("invalid IV: %v", err)}
+		}
+	}
+
+	switch {
+	case len(f.kekFiles) > 0:
+		provider, err := encryption.NewFileKeyProvider(f.kekFiles...)
+		if err != nil {
+			return nil, err
+		}
+		return &walrus.EncryptionOptions{KeyProvider: provider, Suite: suite}, nil
+	case f.keyFile != "":
+		key, err := encryption.ReadKeyFile(f.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &walrus.EncryptionOptions{Key: key, Suite: suite, IV: iv}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func runStore(ctx context.Context, c *cli, args []string) error {
+	var flags storeFlags
+	fs := c.newFlagSet("store", "<file|->")
+	flags.register(fs)
+	size := fs.Int64("size", -1, "exact size of standard input; streams it instead of spooling it to a temporary file, but failed uploads are not retried")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	opts, err := flags.options()
+	if err != nil {
+		return err
+	}
+	if *size >= 0 && fs.Arg(0) != "-" {
+		return &usageError{msg: "-size only applies to standard input"}
+	}
+	var done func()
+	opts.Progress, done = c.transferProgress("Uploading")
+	defer done()
+
+	var resp *walrus.StoreResponse
+	switch path := fs.Arg(0); {
+	case path == "-" && *size >= 0:
+		c.progress("Storing standard input (%d bytes)...", *size)
+		resp, err = c.client.StoreFromReaderWithLengthWithContext(ctx, c.stdin, *size, opts)
+	case path == "-":
+		// Standard input is spooled to a temporary file so that it is not limited by
+		// MaxUnknownLengthUploadSize and failed uploads can be retried
+		file, spoolErr := spoolStdin(c.stdin)
+		if spoolErr != nil {
+			return spoolErr
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+		info, statErr := file.Stat()
+		if statErr != nil {
+			return statErr
+		}
+		c.progress("Storing standard input (%d bytes)...", info.Size())
+		resp, err = c.client.StoreFromReaderWithContext(ctx, file, opts)
+	default:
+		info, statErr := os.Stat(path)
+		if statErr != nil {
+			return statErr
+		}
+		c.progress("Storing %s (%d bytes)...", path, info.Size())
+		resp, err = c.client.StoreFileWithContext(ctx, path, opts)
+	}
+	done()
+	if err != nil {
+		return err
+	}
+
+	c.progress("Stored blob %s", resp.Blob.BlobID)
+	return c.printJSON(resp)
+}
+
+func runStoreURL(ctx context.Context, c *cli, args []string) error {
+	var flags storeFlags
+	fs := c.newFlagSet("store-url", "<url>")
+	flags.register(fs)
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	opts, err := flags.options()
+	if err != nil {
+		return err
+	}
+
+	progress, done := c.transferProgress("Uploading")
+	opts.Progress = progress
+	c.progress("Storing %s...", fs.Arg(0))
+	resp, err := c.client.StoreFromURLWithContext(ctx, fs.Arg(0), opts)
+	done()
+	if err != nil {
+		return err
+	}
+
+	c.progress("Stored blob %s", resp.Blob.BlobID)
+	return c.printJSON(resp)
+}
+
+func runRead(ctx context.Context, c *cli, args []string) error {
+	var flags encryptionFlags
+	fs := c.newFlagSet("read", "<blob-id>")
+	output := fs.String("o", "", "write the blob to this file instead of standard output")
+	flags.register(fs)
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	enc, err := flags.options()
+	if err != nil {
+		return err
+	}
+	progress, done := c.transferProgress("Downloading")
+	defer done()
+	opts := &walrus.ReadOptions{Encryption: enc, Progress: progress}
+
+	blobID := fs.Arg(0)
+	c.progress("Reading blob %s...", blobID)
+	if *output != "" {
+		err := c.client.ReadToFileWithContext(ctx, blobID, *output, opts)
+		done()
+		if err != nil {
+			return err
+		}
+		c.progress("Wrote blob %s to %s", blobID, *output)
+		return nil
+	}
+
+	reader, err := c.client.ReadToReaderWithContext(ctx, blobID, opts)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	n, err := io.Copy(c.stdout, reader)
+	done()
+	if err != nil {
+		return err
+	}
+	c.progress("Read %d bytes", n)
+	return nil
+}
+
+func runHead(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("head", "<blob-id>")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	metadata, err := c.client.HeadWithContext(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return c.printJSON(metadata)
+}
+
+func runAPISpec(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("api-spec", "")
+	publisher := fs.Bool("publisher", false, "query a publisher instead of an aggregator")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	spec, err := c.client.GetAPISpecWithContext(ctx, !*publisher)
+	if err != nil {
+		return err
+	}
+	_, err = c.stdout.Write(spec)
+	return err
+}
+
+func runEndpoints(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("endpoints", "")
+	probe := fs.Bool("probe", true, "probe every endpoint before printing")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	var stats []walrus.EndpointStats
+	if *probe {
+		c.progress("Probing endpoints...")
+		stats = c.client.CheckEndpoints(ctx)
+	} else {
+		stats = c.client.EndpointStats()
+	}
+
+	if *asJSON {
+		return c.printJSON(stats)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tHEALTHY\tLATENCY\tVERSION\tLAST ERROR")
+	for _, s := range stats {
+		latency := "-"
+		if s.Latency > 0 {
+			latency = s.Latency.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\t%s\t%s\n", s.URL, s.Healthy, latency, s.Version, s.LastError)
+	}
+	return w.Flush()
+}
+
+// spoolStdin copies standard input into a temporary file positioned at its start. The caller
+// must close and remove the file.
+func spoolStdin(stdin io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp("", "walrus-go-stdin-*")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(file, stdin)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to read standard input: %w", err)
+	}
+	return file, nil
+}
+
+// stringList is a flag that can be repeated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeWalrus is a minimal in-memory aggregator and publisher
+type fakeWalrus struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+	query string
+}
+
+func newFakeWalrus(t *testing.T) (*fakeWalrus, *httptest.Server) {
+	f := &fakeWalrus{blobs: make(map[string][]byte)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/blobs", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id := "blob-" + string(rune('a'+len(f.blobs)))
+		f.blobs[id] = data
+		f.query = r.URL.RawQuery
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"newlyCreated": map[string]interface{}{
+				"blobObject": map[string]interface{}{"blobId": id, "size": len(data)},
+			},
+		})
+	})
+	mux.HandleFunc("/v1/blobs/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		data, ok := f.blobs[strings.TrimPrefix(r.URL.Path, "/v1/blobs/")]
+		f.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(data)
+	})
+	mux.HandleFunc("/v1/api", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"info":{"version":"1.0.0"}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+// runCLI runs the command line against server and returns the exit code, stdout and stderr
+func runCLI(t *testing.T, server *httptest.Server, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	global := []string{"-aggregator", server.URL, "-publisher", server.URL}
+	var stdout, stderr bytes.Buffer
+	code := run(append(global, args...), strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestStoreAndRead(t *testing.T) {
+	fake, server := newFakeWalrus(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hello.txt")
+	if err := os.WriteFile(path, []byte("hello walrus"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, server, "", "store", "-epochs", "3", "-deletable", "-send-object-to", "0x1", path)
+	if code != 0 {
+		t.Fatalf("store exited with %d: %s", code, stderr)
+	}
+	var resp struct {
+		BlobInfo struct {
+			BlobID string `json:"blobId"`
+		} `json:"blobInfo"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("store printed invalid JSON: %v\n%s", err, stdout)
+	}
+	if resp.BlobInfo.BlobID != "blob-a" {
+		t.Errorf("Expected blob ID blob-a, got %q", resp.BlobInfo.BlobID)
+	}
+	if !strings.Contains(stderr, "Storing "+path+" (12 bytes)") {
+		t.Errorf("Expected progress on stderr, got: %s", stderr)
+	}
+	if fake.query != "deletable=true&epochs=3&send_object_to=0x1" {
+		t.Errorf("Unexpected store query: %s", fake.query)
+	}
+
+	code, stdout, stderr = runCLI(t, server, "", "read", "blob-a")
+	if code != 0 || stdout != "hello walrus" {
+		t.Errorf("read exited with %d, stdout %q, stderr %s", code, stdout, stderr)
+	}
+
+	output := filepath.Join(dir, "out.txt")
+	if code, _, stderr = runCLI(t, server, "", "-quiet", "read", "-o", output, "blob-a"); code != 0 || stderr != "" {
+		t.Fatalf("read -o exited with %d: %s", code, stderr)
+	}
+	if data, _ := os.ReadFile(output); string(data) != "hello walrus" {
+		t.Errorf("Unexpected file content: %q", data)
+	}
+}
+
+func TestStoreEncryptedFromStdin(t *testing.T) {
+	fake, server := newFakeWalrus(t)
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(hex.EncodeToString(bytes.Repeat([]byte{7}, 32))+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keyFlag := range []string{"-key-file", "-kek-file"} {
+		code, _, stderr := runCLI(t, server, "secret data", "store", keyFlag, keyFile, "-")
+		if code != 0 {
+			t.Fatalf("store %s exited with %d: %s", keyFlag, code, stderr)
+		}
+	}
+
+	for id, keyFlag := range map[string]string{"blob-a": "-key-file", "blob-b": "-kek-file"} {
+		if bytes.Contains(fake.blobs[id], []byte("secret data")) {
+			t.Errorf("Blob %s was stored in plaintext", id)
+		}
+		code, stdout, stderr := runCLI(t, server, "", "read", keyFlag, keyFile, id)
+		if code != 0 || stdout != "secret data" {
+			t.Errorf("read %s exited with %d, stdout %q, stderr %s", id, code, stdout, stderr)
+		}
+	}
+}
+
+func TestStoreLargeStdin(t *testing.T) {
+	fake, server := newFakeWalrus(t)
+	data := strings.Repeat("0123456789abcdef", 6<<20/16)
+
+	code, _, stderr := runCLI(t, server, data, "store", "-")
+	if code != 0 {
+		t.Fatalf("store exited with %d: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, "Storing standard input (6291456 bytes)") || !strings.Contains(stderr, "Uploading: 6.0 MiB / 6.0 MiB (100%)") {
+		t.Errorf("Expected upload progress on stderr, got: %s", stderr)
+	}
+	if string(fake.blobs["blob-a"]) != data {
+		t.Errorf("Expected all of standard input to be stored, got %d bytes", len(fake.blobs["blob-a"]))
+	}
+
+	code, _, stderr = runCLI(t, server, "streamed", "store", "-size", "8", "-")
+	if code != 0 || string(fake.blobs["blob-b"]) != "streamed" {
+		t.Errorf("store -size exited with %d: %s", code, stderr)
+	}
+
+	code, _, stderr = runCLI(t, server, "", "read", "-o", filepath.Join(t.TempDir(), "out"), "blob-b")
+	if code != 0 || !strings.Contains(stderr, "Downloading: 8 B / 8 B (100%)") {
+		t.Errorf("Expected download progress on stderr, got %d: %s", code, stderr)
+	}
+}
+
+func TestStoreURL(t *testing.T) {
+	_, server := newFakeWalrus(t)
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("remote content"))
+	}))
+	defer source.Close()
+
+	code, stdout, stderr := runCLI(t, server, "", "store-url", source.URL)
+	if code != 0 || !strings.Contains(stdout, `"blobId": "blob-a"`) {
+		t.Fatalf("store-url exited with %d, stdout %s, stderr %s", code, stdout, stderr)
+	}
+
+	if code, stdout, _ = runCLI(t, server, "", "read", "blob-a"); stdout != "remote content" {
+		t.Errorf("read exited with %d, stdout %q", code, stdout)
+	}
+}
+
+func TestHeadAPISpecEndpoints(t *testing.T) {
+	_, server := newFakeWalrus(t)
+	runCLI(t, server, "data", "store", "-")
+
+	code, stdout, stderr := runCLI(t, server, "", "head", "blob-a")
+	if code != 0 || !strings.Contains(stdout, `"content-length": 4`) {
+		t.Errorf("head exited with %d, stdout %s, stderr %s", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCLI(t, server, "", "api-spec", "-publisher")
+	if code != 0 || !strings.Contains(stdout, "1.0.0") {
+		t.Errorf("api-spec exited with %d, stdout %s", code, stdout)
+	}
+
+	code, stdout, _ = runCLI(t, server, "", "endpoints")
+	if code != 0 || !strings.Contains(stdout, server.URL) || !strings.Contains(stdout, "1.0.0") {
+		t.Errorf("endpoints exited with %d, stdout %s", code, stdout)
+	}
+
+	code, stdout, _ = runCLI(t, server, "", "endpoints", "-json", "-probe=false")
+	var stats []map[string]interface{}
+	if code != 0 || json.Unmarshal([]byte(stdout), &stats) != nil || len(stats) != 1 {
+		t.Errorf("endpoints -json exited with %d, stdout %s", code, stdout)
+	}
+}
+
+func TestErrorsAndUsage(t *testing.T) {
+	_, server := newFakeWalrus(t)
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "no command", args: nil, code: 2},
+		{name: "unknown command", args: []string{"delete"}, code: 2},
+		{name: "missing argument", args: []string{"read"}, code: 2},
+		{name: "unknown flag", args: []string{"store", "-compress", "-"}, code: 2},
+		{name: "size of a file", args: []string{"store", "-size", "4", "file.txt"}, code: 2},
+		{name: "bad cipher", args: []string{"read", "-cipher", "DES", "blob-a"}, code: 2},
+		{name: "missing blob", args: []string{"read", "missing"}, code: 1},
+		{name: "missing file", args: []string{"store", "/does/not/exist"}, code: 1},
+		{name: "help", args: []string{"-h"}, code: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCLI(t, server, "", tt.args...)
+			if code != tt.code {
+				t.Errorf("Expected exit code %d, got %d: %s", tt.code, code, stderr)
+			}
+		})
+	}
+
+	var stderr bytes.Buffer
+	if code := run([]string{"-network", "devnet", "head", "x"}, nil, io.Discard, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown network, got %d", code)
+	}
+}
@@ -0,0 +1,185 @@
+package walrus_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/namihq/walrus-go/encryption"
+)
+
+// partSuffix ends the name of the file ReadToFile downloads a blob into
+const partSuffix = ".part"
+
+// partFilePath returns the file the blob blobID is downloaded into for ReadToFile to filePath.
+// It names the blob, so that a partial download is only ever resumed for the same blob.
+func partFilePath(filePath, blobID string) string {
+	return filePath + "." + url.PathEscape(blobID) + partSuffix
+}
+
+// readToFile implements ReadToFile. The stored content is downloaded into the part file of
+// filePath and blobID, resuming where it broke off if the response fails mid-stream, and then
+// moved to filePath: renamed for plain blobs, or decrypted into a temporary file that replaces
+// filePath for encrypted ones. filePath is therefore only replaced by a complete blob. A failed
+// download keeps the part file, and the next call for the same blob and filePath resumes from
+// its end.
+func (c *Client) readToFile(ctx context.Context, blobID, filePath string, opts *ReadOptions) error {
+	var cipher encryption.ContentCipher
+	if opts != nil && opts.Encryption != nil {
+		var err error
+		if cipher, err = opts.Encryption.getCipher(); err != nil {
+			return fmt.Errorf("failed to create cipher: %w", err)
+		}
+	}
+
+	partPath := partFilePath(filePath, blobID)
+	partFile, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer partFile.Close()
+
+	offset, err := partFile.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	err = c.downloadResumable(ctx, blobID, partFile, offset, opts.progress())
+	var apiErr *APIError
+	if offset > 0 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// The part file is at least as long as the blob, so it is no partial download of it
+		if err := partFile.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := partFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		err = c.downloadResumable(ctx, blobID, partFile, 0, opts.progress())
+	}
+	if err != nil {
+		return err
+	}
+
+	if cipher == nil {
+		if err := partFile.Close(); err != nil {
+			return err
+		}
+		return os.Rename(partPath, filePath)
+	}
+
+	// The ciphertext is complete, so decryption can't be cut short by the network
+	if _, err := partFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if err := cipher.DecryptStream(partFile, tmpFile); err != nil {
+		return fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
+		return err
+	}
+	return os.Remove(partPath)
+}
+
+// downloadResumable writes the stored content of blobID from offset on to w. When the response
+// breaks off mid-stream, the download resumes from the last byte written with a Range request, preferring
+// other aggregators than the one that failed. Whether and when to resume is decided by the
+// client's RetryPolicy, as for failed requests. If the download fails, the returned error is a
+// *MultiError when the response broke off.
+func (c *Client) downloadResumable(ctx context.Context, blobID string, w io.Writer, offset int64, progress ProgressFunc) error {
+	var resp *http.Response
+	var baseURL string
+	if offset > 0 {
+		var err error
+		if resp, baseURL, err = c.readRangeFrom(ctx, blobID, offset, -1, c.AggregatorURL); err != nil {
+			return err
+		}
+	} else {
+		urlStr := fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+		if err != nil {
+			return err
+		}
+		if resp, baseURL, err = c.doReadFrom(ctx, req, c.AggregatorURL); err != nil {
+			return err
+		}
+	}
+
+	// A partial response's length counts from offset
+	total := resp.ContentLength
+	if total >= 0 && resp.StatusCode == http.StatusPartialContent {
+		total += offset
+	}
+	policy := c.getRetryPolicy()
+	start := time.Now()
+	written := offset
+	var failures []error
+
+	for attempt := 1; ; attempt++ {
+		body := &readErrorReader{r: resp.Body}
+		var src io.Reader = body
+		if progress != nil {
+			// Progress continues from the bytes already written
+			src = &progressReader{r: body, progress: progress, transferred: written, total: total}
+		}
+		n, err := io.Copy(w, src)
+		resp.Body.Close()
+		written += n
+
+		if err != nil && body.err == nil {
+			return fmt.Errorf("failed to write download: %w", err)
+		}
+		if err == nil && total >= 0 && written < total {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("request canceled: %w", ctxErr)
+		}
+
+		attemptErr := &APIError{
+			StatusCode: resp.StatusCode,
+			URL:        resp.Request.URL.String(),
+			Attempt:    attempt,
+			Err:        fmt.Errorf("response broke off after %d bytes: %w", written, err),
+		}
+		c.endpoints.recordFailure(baseURL, attemptErr)
+		failures = append(failures, attemptErr)
+
+		delay, retry := policy.NextDelay(attempt, time.Since(start), attemptErr)
+		if !retry {
+			return &MultiError{Errors: failures}
+		}
+		if c.retryHook != nil {
+			c.retryHook(RetryEvent{Attempt: attempt, Err: attemptErr, Delay: delay})
+		}
+		if err := sleepWithContext(ctx, delay); err != nil {
+			return fmt.Errorf("download canceled after %d bytes (last error: %v): %w", written, attemptErr, err)
+		}
+
+		urls := c.AggregatorURL
+		if others := removeString(append([]string(nil), urls...), baseURL); len(others) > 0 {
+			urls = others
+		}
+		if resp, baseURL, err = c.readRangeFrom(ctx, blobID, written, -1, urls); err != nil {
+			return fmt.Errorf("failed to resume download after %d bytes: %w", written, err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package walrus_go
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/namihq/walrus-go/walrustest"
+)
+
+// rangeRequests returns the Range headers of the blob reads received by server
+func rangeRequests(server *walrustest.Server) []string {
+	var ranges []string
+	for _, r := range server.Requests() {
+		if r.Method == http.MethodGet {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+	}
+	return ranges
+}
+
+// TestReadToFileResume tests that a download that breaks off resumes from the last byte written
+func TestReadToFileResume(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+
+	data := make([]byte, 64*1024)
+	rand.Read(data)
+	blobID := server.PutBlob(data, 1)
+	server.InjectFault(walrustest.Fault{Method: http.MethodGet, TruncateAfter: 1000, Times: 1})
+
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithRetryConfig(2, time.Millisecond))
+
+	var progress progressRecorder
+	filePath := filepath.Join(t.TempDir(), "blob")
+	if err := client.ReadToFile(blobID, filePath, &ReadOptions{Progress: progress.record}); err != nil {
+		t.Fatalf("ReadToFile failed: %v", err)
+	}
+	if written, _ := os.ReadFile(filePath); !bytes.Equal(written, data) {
+		t.Errorf("Downloaded file differs from the blob")
+	}
+	if _, err := os.Stat(partFilePath(filePath, blobID)); !os.IsNotExist(err) {
+		t.Errorf("Expected the .part file to be removed, got %v", err)
+	}
+
+	ranges := rangeRequests(server)
+	if len(ranges) != 2 || ranges[0] != "" || ranges[1] != "bytes=1000-" {
+		t.Errorf("Expected a full read and a resumed range, got %q", ranges)
+	}
+
+	// Progress continues where the broken response stopped
+	progress.check(t, "ReadToFile", int64(len(data)), int64(len(data)))
+}
+
+// TestReadToFileResumeOtherAggregator tests that a download resumes on another aggregator
+func TestReadToFileResumeOtherAggregator(t *testing.T) {
+	broken := walrustest.NewServer()
+	defer broken.Close()
+	good := walrustest.NewServer()
+	defer good.Close()
+
+	data := make([]byte, 32*1024)
+	rand.Read(data)
+	broken.PutBlob(data, 1)
+	blobID := good.PutBlob(data, 1)
+	broken.InjectFault(walrustest.Fault{Method: http.MethodGet, TruncateAfter: 5000})
+
+	client := NewClient(WithAggregatorURLs([]string{broken.URL, good.URL}), WithRetryConfig(2, time.Millisecond))
+
+	filePath := filepath.Join(t.TempDir(), "blob")
+	if err := client.ReadToFile(blobID, filePath, nil); err != nil {
+		t.Fatalf("ReadToFile failed: %v", err)
+	}
+	if written, _ := os.ReadFile(filePath); !bytes.Equal(written, data) {
+		t.Errorf("Downloaded file differs from the blob")
+	}
+	if ranges := rangeRequests(good); len(ranges) != 1 || ranges[0] != "bytes=5000-" {
+		t.Errorf("Expected the download to resume on the other aggregator, got %q", ranges)
+	}
+}
+
+// TestReadToFileResumeEncrypted tests that encrypted downloads resume the ciphertext
+func TestReadToFileResumeEncrypted(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(
+		WithAggregatorURLs([]string{server.URL}),
+		WithPublisherURLs([]string{server.URL}),
+		WithRetryConfig(2, time.Millisecond),
+	)
+
+	data := make([]byte, 200*1024)
+	rand.Read(data)
+	encOpts := &EncryptionOptions{Key: make([]byte, 32)}
+	resp, err := client.Store(data, &StoreOptions{Encryption: encOpts})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	server.InjectFault(walrustest.Fault{Method: http.MethodGet, TruncateAfter: 70000, Times: 1})
+
+	filePath := filepath.Join(t.TempDir(), "blob")
+	if err := client.ReadToFile(resp.Blob.BlobID, filePath, &ReadOptions{Encryption: encOpts}); err != nil {
+		t.Fatalf("ReadToFile failed: %v", err)
+	}
+	if written, _ := os.ReadFile(filePath); !bytes.Equal(written, data) {
+		t.Errorf("Decrypted file differs from the original content")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(filePath)); len(entries) != 1 {
+		t.Errorf("Expected only the downloaded file, got %d entries", len(entries))
+	}
+}
+
+// TestReadToFileResumeFailure tests that a download that can't be completed leaves the target
+// untouched, and that the next call resumes from what it downloaded
+func TestReadToFileResumeFailure(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+
+	data := make([]byte, 10000)
+	rand.Read(data)
+	blobID := server.PutBlob(data, 1)
+	server.InjectFault(walrustest.Fault{Method: http.MethodGet, TruncateAfter: 100})
+
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithRetryConfig(1, time.Millisecond))
+
+	filePath := filepath.Join(t.TempDir(), "blob")
+	if err := os.WriteFile(filePath, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.ReadToFile(blobID, filePath, nil)
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) || len(multiErr.Errors) != 2 {
+		t.Fatalf("Expected a MultiError with two broken responses, got %v", err)
+	}
+	if written, _ := os.ReadFile(filePath); string(written) != "previous" {
+		t.Errorf("Expected the file to be unchanged, got %d bytes", len(written))
+	}
+	if info, err := os.Stat(partFilePath(filePath, blobID)); err != nil || info.Size() != 200 {
+		t.Fatalf("Expected the .part file to keep the 200 bytes downloaded, got %v", err)
+	}
+
+	server.ClearFaults()
+	if err := client.ReadToFile(blobID, filePath, nil); err != nil {
+		t.Fatalf("ReadToFile failed: %v", err)
+	}
+	if written, _ := os.ReadFile(filePath); !bytes.Equal(written, data) {
+		t.Errorf("Downloaded file differs from the blob")
+	}
+	if ranges := rangeRequests(server); len(ranges) != 3 || ranges[2] != "bytes=200-" {
+		t.Errorf("Expected the next call to resume after 200 bytes, got %q", ranges)
+	}
+	if _, err := os.Stat(partFilePath(filePath, blobID)); !os.IsNotExist(err) {
+		t.Errorf("Expected the .part file to be removed, got %v", err)
+	}
+}
+
+// TestReadToFileStalePart tests that a .part file longer than the blob is replaced by a new download
+func TestReadToFileStalePart(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+
+	data := []byte("shorter than the stale part")
+	blobID := server.PutBlob(data, 1)
+	client := NewClient(WithAggregatorURLs([]string{server.URL}))
+
+	filePath := filepath.Join(t.TempDir(), "blob")
+	if err := os.WriteFile(partFilePath(filePath, blobID), bytes.Repeat([]byte("x"), 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ReadToFile(blobID, filePath, nil); err != nil {
+		t.Fatalf("ReadToFile failed: %v", err)
+	}
+	if written, _ := os.ReadFile(filePath); !bytes.Equal(written, data) {
+		t.Errorf("Expected file content %q, got %q", data, written)
+	}
+	if ranges := rangeRequests(server); len(ranges) != 2 || ranges[0] != "bytes=100-" || ranges[1] != "" {
+		t.Errorf("Expected a rejected range and a full read, got %q", ranges)
+	}
+}
+
+// TestReadToFileOtherBlobPart tests that the partial download of one blob is not resumed for another
+func TestReadToFileOtherBlobPart(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+
+	first := bytes.Repeat([]byte("A"), 1000)
+	second := bytes.Repeat([]byte("B"), 1000)
+	firstID := server.PutBlob(first, 1)
+	secondID := server.PutBlob(second, 1)
+	server.InjectFault(walrustest.Fault{Method: http.MethodGet, TruncateAfter: 10, Times: 1})
+
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithRetryConfig(0, time.Millisecond))
+	filePath := filepath.Join(t.TempDir(), "blob")
+	if err := client.ReadToFile(firstID, filePath, nil); err == nil {
+		t.Fatal("Expected the broken download to fail")
+	}
+
+	if err := client.ReadToFile(secondID, filePath, nil); err != nil {
+		t.Fatalf("ReadToFile failed: %v", err)
+	}
+	if written, _ := os.ReadFile(filePath); !bytes.Equal(written, second) {
+		t.Errorf("Expected the content of the second blob, got %q", written)
+	}
+	if ranges := rangeRequests(server); len(ranges) != 2 || ranges[1] != "" {
+		t.Errorf("Expected a full read of the second blob, got %q", ranges)
+	}
+	if info, err := os.Stat(partFilePath(filePath, firstID)); err != nil || info.Size() != 10 {
+		t.Errorf("Expected the partial download of the first blob to be kept, got %v", err)
+	}
+}
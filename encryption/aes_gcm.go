@@ -1,172 +1,308 @@
 package encryption
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 )
 
 // gcmContentCipher implements ContentCipher interface
+//
+// Streams are written in a versioned, chunked format following the STREAM construction:
+//
+//	header: magic "WLRS" | version (1 byte) | suite (1 byte) | chunk size (uint32, big endian) | nonce prefix (8 bytes)
+//	chunks: AES-GCM(plaintext chunk), nonce = nonce prefix | chunk counter (uint32, big endian)
+//
+// Every chunk is authenticated together with the header and a flag marking the final chunk,
+// so reordered, truncated or extended streams fail to decrypt.
 type gcmContentCipher struct {
 	key []byte
 	gcm cipher.AEAD
+
+	// allowLegacy lets DecryptStream read the unversioned format written by earlier releases
+	allowLegacy bool
+}
+
+// GCMOption configures a GCM content cipher
+type GCMOption func(*gcmContentCipher)
+
+// WithLegacyGCMDecryption allows DecryptStream to read streams written in the unversioned
+// format used by earlier releases. That format reuses a single nonce for every chunk and cannot
+// detect reordered or truncated chunks, so it should only be enabled to migrate existing blobs.
+// Encryption always uses the current format.
+func WithLegacyGCMDecryption() GCMOption {
+	return func(c *gcmContentCipher) {
+		c.allowLegacy = true
+	}
 }
 
 const (
-	// Default buffer size (32KB)
+	// Default buffer size (32KB), the chunk size of the legacy format
 	defaultBufferSize = 32 * 1024
+
+	// gcmChunkSize is the plaintext size of each chunk written by EncryptStream
+	gcmChunkSize = 64 * 1024
+
+	// gcmMaxChunkSize bounds the chunk size accepted from a stream header
+	gcmMaxChunkSize = 16 * 1024 * 1024
+
+	// gcmStreamMagic identifies streams in the versioned format
+	gcmStreamMagic = "WLRS"
+
+	gcmStreamVersion   = 1
+	gcmSuiteAESGCM     = 1
+	gcmNoncePrefixSize = 8
+	gcmHeaderSize      = len(gcmStreamMagic) + 2 + 4 + gcmNoncePrefixSize
 )
 
+// gcmStreamHeader holds the parameters of a versioned GCM stream
+type gcmStreamHeader struct {
+	chunkSize   int
+	noncePrefix [gcmNoncePrefixSize]byte
+	raw         []byte
+}
+
+// newGCMStreamHeader creates a header with a random nonce prefix
+func newGCMStreamHeader(chunkSize int) (*gcmStreamHeader, error) {
+	h := &gcmStreamHeader{chunkSize: chunkSize}
+	if _, err := rand.Read(h.noncePrefix[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce prefix: %w", err)
+	}
+
+	h.raw = make([]byte, 0, gcmHeaderSize)
+	h.raw = append(h.raw, gcmStreamMagic...)
+	h.raw = append(h.raw, gcmStreamVersion, gcmSuiteAESGCM)
+	h.raw = binary.BigEndian.AppendUint32(h.raw, uint32(chunkSize))
+	h.raw = append(h.raw, h.noncePrefix[:]...)
+	return h, nil
+}
+
+// parseGCMStreamHeader parses a header whose magic has already been verified
+func parseGCMStreamHeader(raw []byte) (*gcmStreamHeader, error) {
+	if len(raw) != gcmHeaderSize {
+		return nil, fmt.Errorf("invalid stream header size: %d", len(raw))
+	}
+
+	magicLen := len(gcmStreamMagic)
+	if version := raw[magicLen]; version != gcmStreamVersion {
+		return nil, fmt.Errorf("unsupported stream version: %d", version)
+	}
+	if suite := raw[magicLen+1]; suite != gcmSuiteAESGCM {
+		return nil, fmt.Errorf("unsupported stream cipher suite: %d", suite)
+	}
+
+	chunkSize := binary.BigEndian.Uint32(raw[magicLen+2:])
+	if chunkSize == 0 || chunkSize > gcmMaxChunkSize {
+		return nil, fmt.Errorf("invalid chunk size: %d", chunkSize)
+	}
+
+	h := &gcmStreamHeader{
+		chunkSize: int(chunkSize),
+		raw:       append([]byte(nil), raw...),
+	}
+	copy(h.noncePrefix[:], raw[magicLen+6:])
+	return h, nil
+}
+
+// nonce returns the nonce of the chunk at index counter
+func (h *gcmStreamHeader) nonce(counter uint32) []byte {
+	nonce := make([]byte, 0, gcmNoncePrefixSize+4)
+	nonce = append(nonce, h.noncePrefix[:]...)
+	return binary.BigEndian.AppendUint32(nonce, counter)
+}
+
+// aad returns the additional data authenticated with each chunk
+func (h *gcmStreamHeader) aad(final bool) []byte {
+	aad := make([]byte, 0, len(h.raw)+1)
+	aad = append(aad, h.raw...)
+	if final {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+// EncryptStream reads plaintext from src and writes it to dst in the versioned chunked format
 func (c *gcmContentCipher) EncryptStream(src io.Reader, dst io.Writer) error {
 	if c.gcm == nil {
 		return fmt.Errorf("gcm cipher is not initialized")
 	}
 
-	// Generate a random key for content encryption
-	contentKey := make([]byte, 32)
-	if _, err := rand.Read(contentKey); err != nil {
-		return fmt.Errorf("failed to generate content key: %w", err)
+	header, err := newGCMStreamHeader(gcmChunkSize)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Write(header.raw); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
 	}
 
-	// Generate a random nonce
-	nonce := make([]byte, c.gcm.NonceSize())
-	if _, err := rand.Read(nonce); err != nil {
-		return fmt.Errorf("failed to generate nonce: %w", err)
-	}
-
-	// Write nonce first
-	if _, err := dst.Write(nonce); err != nil {
-		return fmt.Errorf("failed to write nonce: %w", err)
-	}
-
-	// Create a pipe for streaming
-	pr, pw := io.Pipe()
-
-	// Start a goroutine to handle encryption
-	errCh := make(chan error, 1)
-	go func() {
-		defer pw.Close()
-		
-		// Calculate buffer size to account for maximum possible encryption overhead
-		buf := make([]byte, defaultBufferSize)
-		// Pre-allocate a larger buffer for the ciphertext that includes GCM overhead
-		ciphertextBuf := make([]byte, 0, defaultBufferSize+c.gcm.Overhead())
-		
-		for {
-			n, err := src.Read(buf)
-			if n > 0 {
-				// Encrypt the chunk, using the pre-allocated buffer
-				ciphertext := c.gcm.Seal(ciphertextBuf[:0], nonce, buf[:n], nil)
-				
-				// Write the encrypted chunk
-				if _, err := pw.Write(ciphertext); err != nil {
-					errCh <- fmt.Errorf("failed to write encrypted chunk: %w", err)
-					return
-				}
-			}
-			if err == io.EOF {
-				errCh <- nil
-				return
-			}
-			if err != nil {
-				errCh <- fmt.Errorf("failed to read source: %w", err)
-				return
+	// Read one chunk ahead, since the final chunk has to be sealed differently
+	cur := make([]byte, header.chunkSize)
+	next := make([]byte, header.chunkSize)
+	ciphertextBuf := make([]byte, 0, header.chunkSize+c.gcm.Overhead())
+
+	n, err := readChunk(src, cur)
+	if err != nil {
+		return fmt.Errorf("failed to read source: %w", err)
+	}
+
+	for counter := uint64(0); ; counter++ {
+		if counter > math.MaxUint32 {
+			return fmt.Errorf("stream exceeds the maximum number of chunks")
+		}
+
+		m := 0
+		if n == len(cur) {
+			if m, err = readChunk(src, next); err != nil {
+				return fmt.Errorf("failed to read source: %w", err)
 			}
 		}
-	}()
+		final := m == 0
+
+		ciphertext := c.gcm.Seal(ciphertextBuf[:0], header.nonce(uint32(counter)), cur[:n], header.aad(final))
+		if _, err := dst.Write(ciphertext); err != nil {
+			return fmt.Errorf("failed to write encrypted chunk: %w", err)
+		}
+
+		if final {
+			return nil
+		}
+		cur, next = next, cur
+		n = m
+	}
+}
+
+// DecryptStream reads ciphertext from src and writes decrypted data to dst
+func (c *gcmContentCipher) DecryptStream(src io.Reader, dst io.Writer) error {
+	if c.gcm == nil {
+		return fmt.Errorf("gcm cipher is not initialized")
+	}
+
+	magic := make([]byte, len(gcmStreamMagic))
+	if _, err := io.ReadFull(src, magic); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
 
-	// Copy encrypted data to destination
-	if _, err := io.Copy(dst, pr); err != nil {
-		return fmt.Errorf("failed to copy encrypted data: %w", err)
+	if string(magic) != gcmStreamMagic {
+		if !c.allowLegacy {
+			return fmt.Errorf("unrecognized stream header, legacy streams require WithLegacyGCMDecryption")
+		}
+		// The legacy format starts directly with the nonce
+		return c.decryptLegacyStream(io.MultiReader(bytes.NewReader(magic), src), dst)
 	}
 
-	// Check for encryption errors
-	if err := <-errCh; err != nil {
+	raw := make([]byte, gcmHeaderSize)
+	copy(raw, magic)
+	if _, err := io.ReadFull(src, raw[len(magic):]); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	header, err := parseGCMStreamHeader(raw)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	cur := make([]byte, header.chunkSize+c.gcm.Overhead())
+	next := make([]byte, len(cur))
+	plaintextBuf := make([]byte, 0, header.chunkSize)
+
+	n, err := readChunk(src, cur)
+	if err != nil {
+		return fmt.Errorf("failed to read source: %w", err)
+	}
+
+	for counter := uint64(0); ; counter++ {
+		if counter > math.MaxUint32 {
+			return fmt.Errorf("stream exceeds the maximum number of chunks")
+		}
+
+		m := 0
+		if n == len(cur) {
+			if m, err = readChunk(src, next); err != nil {
+				return fmt.Errorf("failed to read source: %w", err)
+			}
+		}
+		final := m == 0
+
+		if n < c.gcm.Overhead() {
+			return fmt.Errorf("stream is truncated")
+		}
+
+		plaintext, err := c.gcm.Open(plaintextBuf[:0], header.nonce(uint32(counter)), cur[:n], header.aad(final))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk %d: %w", counter, err)
+		}
+		if _, err := dst.Write(plaintext); err != nil {
+			return fmt.Errorf("failed to write decrypted chunk: %w", err)
+		}
+
+		if final {
+			return nil
+		}
+		cur, next = next, cur
+		n = m
+	}
 }
 
-func (c *gcmContentCipher) DecryptStream(src io.Reader, dst io.Writer) error {
+// decryptLegacyStream decrypts the unversioned format: a single nonce followed by
+// defaultBufferSize chunks all sealed with that nonce
+func (c *gcmContentCipher) decryptLegacyStream(src io.Reader, dst io.Writer) error {
 	// Read the nonce
 	nonce := make([]byte, c.gcm.NonceSize())
 	if _, err := io.ReadFull(src, nonce); err != nil {
 		return fmt.Errorf("failed to read nonce: %w", err)
 	}
 
-	// Create a pipe for streaming
-	pr, pw := io.Pipe()
-
-	// Start a goroutine to handle decryption
-	errCh := make(chan error, 1)
-	go func() {
-		defer pw.Close()
-
-		// Calculate exact buffer size for one complete encrypted block
-		// GCM overhead (16 bytes) + block size (which should match encryption)
-		bufSize := defaultBufferSize + c.gcm.Overhead()
-		buf := make([]byte, bufSize)
-
-		for {
-			// Read exact size of one encrypted block
-			n, err := io.ReadFull(src, buf)
-			if err == io.ErrUnexpectedEOF {
-				// Handle last partial block
-				if n > 0 {
-					plaintext, err := c.gcm.Open(nil, nonce, buf[:n], nil)
-					if err != nil {
-						errCh <- fmt.Errorf("failed to decrypt final block: %w", err)
-						return
-					}
-					if _, err := pw.Write(plaintext); err != nil {
-						errCh <- fmt.Errorf("failed to write final decrypted block: %w", err)
-						return
-					}
-				}
-				errCh <- nil
-				return
-			}
-			if err == io.EOF {
-				errCh <- nil
-				return
-			}
-			if err != nil {
-				errCh <- fmt.Errorf("failed to read source: %w", err)
-				return
-			}
+	// Calculate exact buffer size for one complete encrypted block
+	// GCM overhead (16 bytes) + block size (which should match encryption)
+	buf := make([]byte, defaultBufferSize+c.gcm.Overhead())
 
-			// Decrypt the complete block
-			plaintext, err := c.gcm.Open(nil, nonce, buf[:n], nil)
-			if err != nil {
-				errCh <- fmt.Errorf("failed to decrypt block: %w", err)
-				return
-			}
+	for {
+		// Read exact size of one encrypted block
+		n, err := readChunk(src, buf)
+		if err != nil {
+			return fmt.Errorf("failed to read source: %w", err)
+		}
+		if n == 0 {
+			return nil
+		}
 
-			// Write the decrypted block
-			if _, err := pw.Write(plaintext); err != nil {
-				errCh <- fmt.Errorf("failed to write decrypted block: %w", err)
-				return
-			}
+		plaintext, err := c.gcm.Open(nil, nonce, buf[:n], nil)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt block: %w", err)
+		}
+		if _, err := dst.Write(plaintext); err != nil {
+			return fmt.Errorf("failed to write decrypted block: %w", err)
 		}
-	}()
 
-	// Copy decrypted data to destination
-	if _, err := io.Copy(dst, pr); err != nil {
-		return fmt.Errorf("failed to copy decrypted data: %w", err)
+		// A partial block is the last one
+		if n < len(buf) {
+			return nil
+		}
 	}
+}
 
-	// Check for decryption errors
-	if err := <-errCh; err != nil {
-		return err
+// readChunk reads from r until buf is full or r is exhausted. Unlike io.ReadFull, reaching the
+// end of r is not an error; a short count signals it.
+func readChunk(r io.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := r.Read(buf[n:])
+		n += m
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
 	}
-
-	return nil
+	return n, nil
 }
 
-func NewGCMContentCipher(key []byte) (ContentCipher, error) {
+func NewGCMContentCipher(key []byte, opts ...GCMOption) (ContentCipher, error) {
 	if key == nil {
 		return nil, fmt.Errorf("key cannot be nil")
 	}
@@ -188,8 +324,13 @@ func NewGCMContentCipher(key []byte) (ContentCipher, error) {
 		return nil, fmt.Errorf("failed to create GCM: %w", err)
 	}
 
-	return &gcmContentCipher{
+	c := &gcmContentCipher{
 		key: key,
 		gcm: gcm,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
 }
@@ -2,6 +2,8 @@ package encryption
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"io"
 	"strings"
@@ -168,4 +170,161 @@ func TestGCMStreamErrors(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+}
+
+func TestGCMChunkBoundaries(t *testing.T) {
+	sizes := []int{0, 1, gcmChunkSize - 1, gcmChunkSize, gcmChunkSize + 1, 2 * gcmChunkSize, 3*gcmChunkSize + 7}
+
+	key := make([]byte, 32)
+	rand.Read(key)
+	cipher, err := NewGCMContentCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	for _, size := range sizes {
+		t.Run(formatTestName(size), func(t *testing.T) {
+			plaintext := make([]byte, size)
+			rand.Read(plaintext)
+
+			var encrypted bytes.Buffer
+			if err := cipher.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+				t.Fatalf("Encryption failed: %v", err)
+			}
+			if !bytes.HasPrefix(encrypted.Bytes(), []byte(gcmStreamMagic)) {
+				t.Error("Encrypted stream doesn't start with the format magic")
+			}
+
+			var decrypted bytes.Buffer
+			if err := cipher.DecryptStream(bytes.NewReader(encrypted.Bytes()), &decrypted); err != nil {
+				t.Fatalf("Decryption failed: %v", err)
+			}
+			if !bytes.Equal(plaintext, decrypted.Bytes()) {
+				t.Error("Decrypted data doesn't match original")
+			}
+		})
+	}
+}
+
+func TestGCMUniqueNonces(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	cipher, err := NewGCMContentCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	// Identical plaintext chunks must not produce identical ciphertext chunks
+	plaintext := make([]byte, 2*gcmChunkSize)
+	var encrypted bytes.Buffer
+	if err := cipher.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	body := encrypted.Bytes()[gcmHeaderSize:]
+	chunkLen := gcmChunkSize + 16
+	if bytes.Equal(body[:chunkLen], body[chunkLen:2*chunkLen]) {
+		t.Error("Identical plaintext chunks were encrypted with the same nonce")
+	}
+}
+
+func TestGCMStreamIntegrity(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	cipher, err := NewGCMContentCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	plaintext := make([]byte, 3*gcmChunkSize)
+	rand.Read(plaintext)
+	var encrypted bytes.Buffer
+	if err := cipher.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	stream := encrypted.Bytes()
+	header := stream[:gcmHeaderSize]
+	chunkLen := gcmChunkSize + 16
+	chunk := func(i int) []byte {
+		return stream[gcmHeaderSize+i*chunkLen : gcmHeaderSize+(i+1)*chunkLen]
+	}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	tests := []struct {
+		name   string
+		stream []byte
+	}{
+		{name: "truncated at chunk boundary", stream: join(header, chunk(0), chunk(1))},
+		{name: "truncated to header", stream: header},
+		{name: "reordered chunks", stream: join(header, chunk(1), chunk(0), chunk(2))},
+		{name: "duplicated chunk", stream: join(header, chunk(0), chunk(0), chunk(1), chunk(2))},
+		{name: "extended stream", stream: join(stream, chunk(0))},
+		{name: "modified header", stream: join(header[:gcmHeaderSize-1], []byte{header[gcmHeaderSize-1] ^ 1}, stream[gcmHeaderSize:])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decrypted bytes.Buffer
+			if err := cipher.DecryptStream(bytes.NewReader(tt.stream), &decrypted); err == nil {
+				t.Error("Expected decryption error, got none")
+			}
+		})
+	}
+}
+
+func TestGCMLegacyDecryption(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	plaintext := make([]byte, defaultBufferSize+100)
+	rand.Read(plaintext)
+
+	// Build a stream in the legacy layout: one nonce shared by every chunk
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	legacy := append([]byte(nil), nonce...)
+	for start := 0; start < len(plaintext); start += defaultBufferSize {
+		end := start + defaultBufferSize
+		if end > len(plaintext) {
+			end = len(plaintext)
+		}
+		legacy = gcm.Seal(legacy, nonce, plaintext[start:end], nil)
+	}
+
+	strict, err := NewGCMContentCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	if err := strict.DecryptStream(bytes.NewReader(legacy), &bytes.Buffer{}); err == nil {
+		t.Error("Expected legacy stream to be rejected without opt-in")
+	}
+
+	lenient, err := NewGCMContentCipher(key, WithLegacyGCMDecryption())
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	var decrypted bytes.Buffer
+	if err := lenient.DecryptStream(bytes.NewReader(legacy), &decrypted); err != nil {
+		t.Fatalf("Legacy decryption failed: %v", err)
+	}
+	if !bytes.Equal(plaintext, decrypted.Bytes()) {
+		t.Error("Decrypted legacy data doesn't match original")
+	}
+
+	// Current streams are still readable with legacy support enabled
+	var encrypted bytes.Buffer
+	if err := lenient.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	decrypted.Reset()
+	if err := lenient.DecryptStream(bytes.NewReader(encrypted.Bytes()), &decrypted); err != nil {
+		t.Fatalf("Decryption failed: %v", err)
+	}
+	if !bytes.Equal(plaintext, decrypted.Bytes()) {
+		t.Error("Decrypted data doesn't match original")
+	}
+}
@@ -0,0 +1,123 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// envelopeCipher implements ContentCipher with envelope encryption: every stream is encrypted
+// with a fresh random data key, and the data key is stored in the stream header wrapped by a
+// key-encryption key from a KeyProvider.
+//
+//	header: magic "WLRE" | version (1 byte) | suite (1 byte) | key ID length (uint16, big endian) | key ID |
+//	        wrapped key length (uint16, big endian) | wrapped key
+//	body:   AES-GCM stream (see gcmContentCipher) encrypted with the data key
+type envelopeCipher struct {
+	provider KeyProvider
+}
+
+const (
+	// envelopeMagic identifies envelope encrypted streams
+	envelopeMagic = "WLRE"
+
+	envelopeVersion     = 1
+	envelopeDataKeySize = 32
+)
+
+// NewEnvelopeCipher creates a cipher that encrypts each stream with its own AES-256-GCM data
+// key, wrapped by the key-encryption keys of provider
+func NewEnvelopeCipher(provider KeyProvider) (ContentCipher, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("key provider cannot be nil")
+	}
+	return &envelopeCipher{provider: provider}, nil
+}
+
+// EncryptStream generates a data key, writes the envelope header and encrypts src with the data key
+func (c *envelopeCipher) EncryptStream(src io.Reader, dst io.Writer) error {
+	dataKey := make([]byte, envelopeDataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	keyID, wrapped, err := c.provider.WrapKey(dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	if len(keyID) > 0xFFFF || len(wrapped) > 0xFFFF {
+		return fmt.Errorf("wrapped data key is too large")
+	}
+
+	header := make([]byte, 0, len(envelopeMagic)+6+len(keyID)+len(wrapped))
+	header = append(header, envelopeMagic...)
+	header = append(header, envelopeVersion, gcmSuiteAESGCM)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(keyID)))
+	header = append(header, keyID...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+	if _, err := dst.Write(header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	inner, err := NewGCMContentCipher(dataKey)
+	if err != nil {
+		return err
+	}
+	return inner.EncryptStream(src, dst)
+}
+
+// DecryptStream reads the envelope header, unwraps the data key and decrypts the rest of src
+func (c *envelopeCipher) DecryptStream(src io.Reader, dst io.Writer) error {
+	keyID, wrapped, err := readEnvelopeHeader(src)
+	if err != nil {
+		return err
+	}
+
+	dataKey, err := c.provider.UnwrapKey(keyID, wrapped)
+	if err != nil {
+		return fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	inner, err := NewGCMContentCipher(dataKey)
+	if err != nil {
+		return err
+	}
+	return inner.DecryptStream(src, dst)
+}
+
+// readEnvelopeHeader reads an envelope header from src and returns the key ID and wrapped data key
+func readEnvelopeHeader(src io.Reader) (string, []byte, error) {
+	fixed := make([]byte, len(envelopeMagic)+4)
+	if _, err := io.ReadFull(src, fixed); err != nil {
+		return "", nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	magicLen := len(envelopeMagic)
+	if string(fixed[:magicLen]) != envelopeMagic {
+		return "", nil, fmt.Errorf("not an envelope encrypted stream")
+	}
+	if version := fixed[magicLen]; version != envelopeVersion {
+		return "", nil, fmt.Errorf("unsupported envelope version: %d", version)
+	}
+	if suite := fixed[magicLen+1]; suite != gcmSuiteAESGCM {
+		return "", nil, fmt.Errorf("unsupported envelope cipher suite: %d", suite)
+	}
+
+	keyID := make([]byte, binary.BigEndian.Uint16(fixed[magicLen+2:]))
+	if _, err := io.ReadFull(src, keyID); err != nil {
+		return "", nil, fmt.Errorf("failed to read key ID: %w", err)
+	}
+
+	var wrappedLen [2]byte
+	if _, err := io.ReadFull(src, wrappedLen[:]); err != nil {
+		return "", nil, fmt.Errorf("failed to read wrapped key: %w", err)
+	}
+	wrapped := make([]byte, binary.BigEndian.Uint16(wrappedLen[:]))
+	if _, err := io.ReadFull(src, wrapped); err != nil {
+		return "", nil, fmt.Errorf("failed to read wrapped key: %w", err)
+	}
+
+	return string(keyID), wrapped, nil
+}
@@ -0,0 +1,128 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestEnvelopeCipher(t *testing.T) {
+	provider, err := NewMemoryKeyProvider()
+	if err != nil {
+		t.Fatalf("Failed to create key provider: %v", err)
+	}
+
+	cipher, err := NewEnvelopeCipher(provider)
+	if err != nil {
+		t.Fatalf("Failed to create envelope cipher: %v", err)
+	}
+
+	for _, size := range []int{0, 16, 1024, 1048576} {
+		t.Run(formatTestName(size), func(t *testing.T) {
+			plaintext := make([]byte, size)
+			rand.Read(plaintext)
+
+			var encrypted bytes.Buffer
+			if err := cipher.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+				t.Fatalf("Encryption failed: %v", err)
+			}
+
+			var decrypted bytes.Buffer
+			if err := cipher.DecryptStream(bytes.NewReader(encrypted.Bytes()), &decrypted); err != nil {
+				t.Fatalf("Decryption failed: %v", err)
+			}
+			if !bytes.Equal(plaintext, decrypted.Bytes()) {
+				t.Error("Decrypted data doesn't match original")
+			}
+		})
+	}
+}
+
+func TestEnvelopeKeyRotation(t *testing.T) {
+	provider, err := NewMemoryKeyProvider()
+	if err != nil {
+		t.Fatalf("Failed to create key provider: %v", err)
+	}
+	cipher, _ := NewEnvelopeCipher(provider)
+	plaintext := []byte("rotate me")
+
+	var before bytes.Buffer
+	if err := cipher.EncryptStream(bytes.NewReader(plaintext), &before); err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	newID, err := provider.Rotate()
+	if err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+
+	var after bytes.Buffer
+	if err := cipher.EncryptStream(bytes.NewReader(plaintext), &after); err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	oldID, _, err := readEnvelopeHeader(bytes.NewReader(before.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to read header: %v", err)
+	}
+	headerID, _, err := readEnvelopeHeader(bytes.NewReader(after.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to read header: %v", err)
+	}
+	if headerID != newID || oldID == newID {
+		t.Errorf("Expected new blobs to use rotated key %s, got %s (previous %s)", newID, headerID, oldID)
+	}
+
+	// Blobs from before the rotation remain readable
+	for _, encrypted := range [][]byte{before.Bytes(), after.Bytes()} {
+		var decrypted bytes.Buffer
+		if err := cipher.DecryptStream(bytes.NewReader(encrypted), &decrypted); err != nil {
+			t.Fatalf("Decryption failed: %v", err)
+		}
+		if !bytes.Equal(plaintext, decrypted.Bytes()) {
+			t.Error("Decrypted data doesn't match original")
+		}
+	}
+}
+
+func TestEnvelopeErrors(t *testing.T) {
+	provider, _ := NewMemoryKeyProvider()
+	cipher, _ := NewEnvelopeCipher(provider)
+
+	var encrypted bytes.Buffer
+	if err := cipher.EncryptStream(bytes.NewReader([]byte("secret")), &encrypted); err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	otherProvider, _ := NewMemoryKeyProvider()
+	otherCipher, _ := NewEnvelopeCipher(otherProvider)
+
+	// The wrapped key follows the magic, version, suite, key ID length and key ID
+	keyID, _, _ := readEnvelopeHeader(bytes.NewReader(encrypted.Bytes()))
+	wrappedOffset := len(envelopeMagic) + 4 + len(keyID) + 2
+	tamperedKey := append([]byte(nil), encrypted.Bytes()...)
+	tamperedKey[wrappedOffset] ^= 0x01
+
+	tests := []struct {
+		name   string
+		cipher ContentCipher
+		stream []byte
+	}{
+		{name: "unknown key ID", cipher: otherCipher, stream: encrypted.Bytes()},
+		{name: "tampered wrapped key", cipher: cipher, stream: tamperedKey},
+		{name: "not an envelope", cipher: cipher, stream: []byte("WLRS plain gcm stream")},
+		{name: "truncated header", cipher: cipher, stream: encrypted.Bytes()[:8]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cipher.DecryptStream(bytes.NewReader(tt.stream), &bytes.Buffer{}); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+
+	if _, err := NewEnvelopeCipher(nil); err == nil {
+		t.Error("Expected error for nil key provider")
+	}
+}
@@ -0,0 +1,222 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeyProvider wraps and unwraps per-blob data keys with key-encryption keys (KEKs).
+// Implementations may hold several KEKs so that keys can be rotated: new data keys are
+// wrapped with the current KEK while older blobs remain readable through their key ID.
+type KeyProvider interface {
+	// WrapKey encrypts dataKey with the current KEK and returns that KEK's ID
+	WrapKey(dataKey []byte) (keyID string, wrapped []byte, err error)
+
+	// UnwrapKey decrypts a data key that was wrapped by the KEK identified by keyID
+	UnwrapKey(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// keyring is a KeyProvider backed by a set of AES-GCM key-encryption keys
+type keyring struct {
+	mu      sync.RWMutex
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// add registers kek and makes it the primary key if primary is true
+func (k *keyring) add(kek []byte, primary bool) (string, error) {
+	switch len(kek) {
+	case 16, 24, 32:
+		// valid key size
+	default:
+		return "", fmt.Errorf("invalid key size: %d", len(kek))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return "", fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	id := KeyID(kek)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.keys == nil {
+		k.keys = make(map[string]cipher.AEAD)
+	}
+	k.keys[id] = gcm
+	if primary || k.primary == "" {
+		k.primary = id
+	}
+	return id, nil
+}
+
+// WrapKey encrypts dataKey with the primary key-encryption key
+func (k *keyring) WrapKey(dataKey []byte) (string, []byte, error) {
+	k.mu.RLock()
+	id := k.primary
+	gcm := k.keys[id]
+	k.mu.RUnlock()
+
+	if gcm == nil {
+		return "", nil, fmt.Errorf("no key-encryption key configured")
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// Bind the wrapped key to its key ID
+	wrapped := gcm.Seal(nonce, nonce, dataKey, []byte(id))
+	return id, wrapped, nil
+}
+
+// UnwrapKey decrypts a data key wrapped by the key-encryption key identified by keyID
+func (k *keyring) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	k.mu.RLock()
+	gcm := k.keys[keyID]
+	k.mu.RUnlock()
+
+	if gcm == nil {
+		return nil, fmt.Errorf("unknown key-encryption key: %s", keyID)
+	}
+	if len(wrapped) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+
+	nonce, ciphertext := wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():]
+	dataKey, err := gcm.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+// KeyID returns the identifier recorded in blob headers for a key-encryption key.
+// It is derived from the key itself, so the same key always has the same ID.
+func KeyID(kek []byte) string {
+	sum := sha256.Sum256(kek)
+	return hex.EncodeToString(sum[:8])
+}
+
+// MemoryKeyProvider is an in-memory KeyProvider, mainly intended for tests
+type MemoryKeyProvider struct {
+	keyring
+}
+
+// NewMemoryKeyProvider creates a provider with a freshly generated 256-bit key-encryption key
+func NewMemoryKeyProvider() (*MemoryKeyProvider, error) {
+	p := &MemoryKeyProvider{}
+	if _, err := p.Rotate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// AddKey registers an existing key-encryption key. If primary is true, it is used to
+// wrap new data keys from now on.
+func (p *MemoryKeyProvider) AddKey(kek []byte, primary bool) (string, error) {
+	return p.add(kek, primary)
+}
+
+// Rotate generates a new key-encryption key and makes it the primary key. Previous keys are
+// kept so that existing blobs can still be decrypted.
+func (p *MemoryKeyProvider) Rotate() (string, error) {
+	kek := make([]byte, 32)
+	if _, err := rand.Read(kek); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return p.add(kek, true)
+}
+
+// NewFileKeyProvider creates a provider from key files. Each file holds one key-encryption key,
+// either hex or base64 encoded or as raw bytes. The first file is the primary key; the others
+// are only used to unwrap data keys of existing blobs, which allows keys to be rotated.
+func NewFileKeyProvider(paths ...string) (KeyProvider, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("at least one key file is required")
+	}
+
+	k := &keyring{}
+	for _, path := range paths {
+		kek, err := ReadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := k.add(kek, false); err != nil {
+			return nil, fmt.Errorf("invalid key in %s: %w", path, err)
+		}
+	}
+	return k, nil
+}
+
+// NewEnvKeyProvider creates a provider from environment variables holding hex or base64
+// encoded key-encryption keys. The first variable is the primary key; the others are only
+// used to unwrap data keys of existing blobs.
+func NewEnvKeyProvider(names ...string) (KeyProvider, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("at least one environment variable is required")
+	}
+
+	k := &keyring{}
+	for _, name := range names {
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+		kek, err := decodeKey([]byte(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in %s: %w", name, err)
+		}
+		if _, err := k.add(kek, false); err != nil {
+			return nil, fmt.Errorf("invalid key in %s: %w", name, err)
+		}
+	}
+	return k, nil
+}
+
+// ReadKeyFile reads a 128, 192 or 256-bit key from a file, either hex or base64 encoded or as raw bytes
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	key, err := decodeKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key in %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// decodeKey accepts a hex or base64 encoded key, falling back to the raw bytes
+func decodeKey(data []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(data))
+
+	if key, err := hex.DecodeString(text); err == nil && isValidKeySize(len(key)) {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && isValidKeySize(len(key)) {
+		return key, nil
+	}
+	if isValidKeySize(len(data)) {
+		return data, nil
+	}
+	return nil, fmt.Errorf("key must be 16, 24 or 32 bytes, hex or base64 encoded")
+}
+
+func isValidKeySize(size int) bool {
+	return size == 16 || size == 24 || size == 32
+}
@@ -0,0 +1,114 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileKeyProvider(t *testing.T) {
+	dir := t.TempDir()
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	encodings := map[string][]byte{
+		"hex":    []byte(hex.EncodeToString(key) + "\n"),
+		"base64": []byte(base64.StdEncoding.EncodeToString(key)),
+		"raw":    key,
+	}
+
+	for name, content := range encodings {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".key")
+			if err := os.WriteFile(path, content, 0600); err != nil {
+				t.Fatalf("Failed to write key file: %v", err)
+			}
+
+			provider, err := NewFileKeyProvider(path)
+			if err != nil {
+				t.Fatalf("Failed to create key provider: %v", err)
+			}
+
+			dataKey := []byte("0123456789abcdef0123456789abcdef")
+			keyID, wrapped, err := provider.WrapKey(dataKey)
+			if err != nil {
+				t.Fatalf("Failed to wrap key: %v", err)
+			}
+			if keyID != KeyID(key) {
+				t.Errorf("Expected key ID %s, got %s", KeyID(key), keyID)
+			}
+
+			unwrapped, err := provider.UnwrapKey(keyID, wrapped)
+			if err != nil {
+				t.Fatalf("Failed to unwrap key: %v", err)
+			}
+			if !bytes.Equal(unwrapped, dataKey) {
+				t.Error("Unwrapped key doesn't match original")
+			}
+		})
+	}
+}
+
+func TestFileKeyProviderRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := make([]byte, 32)
+	newKey := make([]byte, 32)
+	rand.Read(oldKey)
+	rand.Read(newKey)
+
+	oldPath := filepath.Join(dir, "old.key")
+	newPath := filepath.Join(dir, "new.key")
+	os.WriteFile(oldPath, []byte(hex.EncodeToString(oldKey)), 0600)
+	os.WriteFile(newPath, []byte(hex.EncodeToString(newKey)), 0600)
+
+	oldProvider, err := NewFileKeyProvider(oldPath)
+	if err != nil {
+		t.Fatalf("Failed to create key provider: %v", err)
+	}
+	keyID, wrapped, err := oldProvider.WrapKey(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to wrap key: %v", err)
+	}
+
+	rotated, err := NewFileKeyProvider(newPath, oldPath)
+	if err != nil {
+		t.Fatalf("Failed to create key provider: %v", err)
+	}
+	if _, err := rotated.UnwrapKey(keyID, wrapped); err != nil {
+		t.Errorf("Failed to unwrap key with rotated provider: %v", err)
+	}
+	if newID, _, _ := rotated.WrapKey(make([]byte, 32)); newID != KeyID(newKey) {
+		t.Errorf("Expected rotated provider to wrap with key %s, got %s", KeyID(newKey), newID)
+	}
+}
+
+func TestEnvKeyProvider(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	t.Setenv("WALRUS_TEST_KEK", base64.StdEncoding.EncodeToString(key))
+
+	provider, err := NewEnvKeyProvider("WALRUS_TEST_KEK")
+	if err != nil {
+		t.Fatalf("Failed to create key provider: %v", err)
+	}
+	keyID, _, err := provider.WrapKey(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to wrap key: %v", err)
+	}
+	if keyID != KeyID(key) {
+		t.Errorf("Expected key ID %s, got %s", KeyID(key), keyID)
+	}
+
+	if _, err := NewEnvKeyProvider("WALRUS_TEST_KEK_MISSING"); err == nil {
+		t.Error("Expected error for missing environment variable")
+	}
+
+	t.Setenv("WALRUS_TEST_KEK_INVALID", "not a key")
+	if _, err := NewEnvKeyProvider("WALRUS_TEST_KEK_INVALID"); err == nil {
+		t.Error("Expected error for invalid key")
+	}
+}
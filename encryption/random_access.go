@@ -0,0 +1,204 @@
+package encryption
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// RandomAccessCipher is implemented by ciphers whose format can be decrypted at any offset
+// without processing the stream from the start
+type RandomAccessCipher interface {
+	ContentCipher
+
+	// NewReaderAt returns a reader of the plaintext of the size-byte ciphertext in src. Each
+	// read only fetches and decrypts the chunks that cover the requested range.
+	NewReaderAt(src io.ReaderAt, size int64) (SizeReaderAt, error)
+}
+
+// SizeReaderAt is an io.ReaderAt that knows the size of its content
+type SizeReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// gcmReaderAt decrypts a versioned GCM stream chunk by chunk
+type gcmReaderAt struct {
+	src       io.ReaderAt
+	gcm       *gcmContentCipher
+	header    *gcmStreamHeader
+	chunks    int64
+	plainSize int64
+
+	// The most recently decrypted chunk, so that small sequential reads don't refetch it
+	mu          sync.Mutex
+	cachedIndex int64
+	cached      []byte
+}
+
+// NewReaderAt returns a reader of the plaintext of a versioned GCM stream. Legacy streams
+// cannot be read at random offsets.
+func (c *gcmContentCipher) NewReaderAt(src io.ReaderAt, size int64) (SizeReaderAt, error) {
+	if c.gcm == nil {
+		return nil, fmt.Errorf("gcm cipher is not initialized")
+	}
+	if size < int64(gcmHeaderSize) {
+		return nil, fmt.Errorf("stream is truncated")
+	}
+
+	raw := make([]byte, gcmHeaderSize)
+	if _, err := src.ReadAt(raw, 0); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(raw[:len(gcmStreamMagic)]) != gcmStreamMagic {
+		return nil, fmt.Errorf("random access requires the versioned stream format")
+	}
+	header, err := parseGCMStreamHeader(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every chunk but the last is full; the last one holds at least the authentication tag
+	overhead := int64(c.gcm.Overhead())
+	ciphertextChunk := int64(header.chunkSize) + overhead
+	body := size - int64(gcmHeaderSize)
+	chunks := (body + ciphertextChunk - 1) / ciphertextChunk
+	if chunks == 0 || body-(chunks-1)*ciphertextChunk < overhead {
+		return nil, fmt.Errorf("stream is truncated")
+	}
+
+	return &gcmReaderAt{
+		src:         io.NewSectionReader(src, int64(gcmHeaderSize), body),
+		gcm:         c,
+		header:      header,
+		chunks:      chunks,
+		plainSize:   body - chunks*overhead,
+		cachedIndex: -1,
+	}, nil
+}
+
+// Size returns the plaintext size
+func (r *gcmReaderAt) Size() int64 {
+	return r.plainSize
+}
+
+// ReadAt decrypts the chunks covering p and copies the requested plaintext into it.
+// The ciphertext of all needed chunks is fetched with a single read from the source.
+func (r *gcmReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+	if off >= r.plainSize {
+		return 0, io.EOF
+	}
+
+	end := off + int64(len(p))
+	if end > r.plainSize {
+		end = r.plainSize
+	}
+	if end == off {
+		return 0, nil
+	}
+
+	chunkSize := int64(r.header.chunkSize)
+	first, last := off/chunkSize, (end-1)/chunkSize
+
+	n := 0
+	if plaintext, ok := r.cachedChunk(first); ok {
+		n += copy(p, plaintext[off-first*chunkSize:])
+		first++
+	}
+
+	if first <= last {
+		ciphertextChunk := chunkSize + int64(r.gcm.gcm.Overhead())
+		start := first * ciphertextChunk
+		length := (last - first + 1) * ciphertextChunk
+		ciphertext := make([]byte, length)
+		read, err := r.src.ReadAt(ciphertext, start)
+		if err != nil && err != io.EOF {
+			return n, fmt.Errorf("failed to read chunks: %w", err)
+		}
+		ciphertext = ciphertext[:read]
+
+		for index := first; index <= last; index++ {
+			chunkStart := (index - first) * ciphertextChunk
+			chunkEnd := chunkStart + ciphertextChunk
+			if chunkEnd > int64(len(ciphertext)) {
+				chunkEnd = int64(len(ciphertext))
+			}
+			if chunkStart >= chunkEnd {
+				return n, fmt.Errorf("stream is truncated")
+			}
+
+			final := index == r.chunks-1
+			plaintext, err := r.gcm.gcm.Open(nil, r.header.nonce(uint32(index)), ciphertext[chunkStart:chunkEnd], r.header.aad(final))
+			if err != nil {
+				return n, fmt.Errorf("failed to decrypt chunk %d: %w", index, err)
+			}
+
+			plainOffset := int64(0)
+			if index*chunkSize < off {
+				plainOffset = off - index*chunkSize
+			}
+			n += copy(p[n:], plaintext[plainOffset:])
+
+			if index == last {
+				r.cacheChunk(index, plaintext)
+			}
+		}
+	}
+
+	if off+int64(n) == r.plainSize && n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (r *gcmReaderAt) cachedChunk(index int64) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cached, r.cachedIndex == index
+}
+
+func (r *gcmReaderAt) cacheChunk(index int64, plaintext []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cachedIndex, r.cached = index, plaintext
+}
+
+// NewReaderAt reads the envelope header, unwraps the data key and returns a random access
+// reader of the GCM stream that follows the header
+func (c *envelopeCipher) NewReaderAt(src io.ReaderAt, size int64) (SizeReaderAt, error) {
+	// Buffer the header reads so they are served by a single read from src
+	counter := &countingReader{r: bufio.NewReaderSize(io.NewSectionReader(src, 0, size), 4096)}
+	keyID, wrapped, err := readEnvelopeHeader(counter)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := c.provider.UnwrapKey(keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	inner, err := NewGCMContentCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	headerSize := counter.n
+	return inner.(*gcmContentCipher).NewReaderAt(io.NewSectionReader(src, headerSize, size-headerSize), size-headerSize)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,184 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	mathrand "math/rand"
+	"testing"
+)
+
+// recordingReaderAt records the ranges read from an io.ReaderAt
+type recordingReaderAt struct {
+	r     io.ReaderAt
+	reads [][2]int64
+}
+
+func (r *recordingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.reads = append(r.reads, [2]int64{off, int64(len(p))})
+	return r.r.ReadAt(p, off)
+}
+
+func encryptForTest(t *testing.T, cipher ContentCipher, plaintext []byte) []byte {
+	var encrypted bytes.Buffer
+	if err := cipher.EncryptStream(bytes.NewReader(plaintext), &encrypted); err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+	return encrypted.Bytes()
+}
+
+func TestGCMReaderAt(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	cipher, _ := NewGCMContentCipher(key)
+
+	for _, size := range []int{0, 100, gcmChunkSize, 3*gcmChunkSize + 123} {
+		t.Run(formatTestName(size), func(t *testing.T) {
+			plaintext := make([]byte, size)
+			rand.Read(plaintext)
+			encrypted := encryptForTest(t, cipher, plaintext)
+
+			ra, err := cipher.(RandomAccessCipher).NewReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)))
+			if err != nil {
+				t.Fatalf("Failed to create reader: %v", err)
+			}
+			if ra.Size() != int64(size) {
+				t.Fatalf("Expected plaintext size %d, got %d", size, ra.Size())
+			}
+
+			// Whole content through a section reader
+			all, err := io.ReadAll(io.NewSectionReader(ra, 0, ra.Size()))
+			if err != nil {
+				t.Fatalf("Failed to read content: %v", err)
+			}
+			if !bytes.Equal(all, plaintext) {
+				t.Fatal("Decrypted content doesn't match original")
+			}
+
+			// Random ranges
+			rng := mathrand.New(mathrand.NewSource(int64(size)))
+			for i := 0; i < 50 && size > 0; i++ {
+				off := rng.Int63n(int64(size))
+				buf := make([]byte, rng.Intn(2*gcmChunkSize)+1)
+				n, err := ra.ReadAt(buf, off)
+				if err != nil && err != io.EOF {
+					t.Fatalf("ReadAt(%d, %d) failed: %v", off, len(buf), err)
+				}
+				expected := plaintext[off:]
+				if len(expected) > len(buf) {
+					expected = expected[:len(buf)]
+				}
+				if !bytes.Equal(buf[:n], expected) {
+					t.Fatalf("ReadAt(%d, %d) returned wrong content", off, len(buf))
+				}
+				if n < len(buf) && err != io.EOF {
+					t.Fatalf("ReadAt(%d, %d) returned %d bytes without io.EOF", off, len(buf), n)
+				}
+			}
+		})
+	}
+}
+
+func TestGCMReaderAtFetchesOnlyNeededChunks(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	cipher, _ := NewGCMContentCipher(key)
+
+	plaintext := make([]byte, 10*gcmChunkSize)
+	rand.Read(plaintext)
+	encrypted := encryptForTest(t, cipher, plaintext)
+
+	src := &recordingReaderAt{r: bytes.NewReader(encrypted)}
+	ra, err := cipher.(RandomAccessCipher).NewReaderAt(src, int64(len(encrypted)))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	src.reads = nil
+
+	// A read spanning the boundary of chunks 5 and 6
+	buf := make([]byte, 100)
+	if _, err := ra.ReadAt(buf, 6*gcmChunkSize-50); err != nil {
+		t.Fatalf("ReadAt failed: %v", err)
+	}
+	if !bytes.Equal(buf, plaintext[6*gcmChunkSize-50:6*gcmChunkSize+50]) {
+		t.Error("Decrypted content doesn't match original")
+	}
+
+	chunkLen := int64(gcmChunkSize + 16)
+	expected := [2]int64{int64(gcmHeaderSize) + 5*chunkLen, 2 * chunkLen}
+	if len(src.reads) != 1 || src.reads[0] != expected {
+		t.Errorf("Expected a single read of %v, got %v", expected, src.reads)
+	}
+
+	// A following read within the last chunk is served from the cache
+	src.reads = nil
+	if _, err := ra.ReadAt(buf, 6*gcmChunkSize+50); err != nil {
+		t.Fatalf("ReadAt failed: %v", err)
+	}
+	if len(src.reads) != 0 {
+		t.Errorf("Expected cached chunk to be reused, got reads %v", src.reads)
+	}
+}
+
+func TestGCMReaderAtIntegrity(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	cipher, _ := NewGCMContentCipher(key)
+
+	plaintext := make([]byte, 3*gcmChunkSize)
+	rand.Read(plaintext)
+	encrypted := encryptForTest(t, cipher, plaintext)
+	chunkLen := gcmChunkSize + 16
+
+	// Dropping the final chunk makes the previous chunk look final
+	truncated := encrypted[:gcmHeaderSize+2*chunkLen]
+	ra, err := cipher.(RandomAccessCipher).NewReaderAt(bytes.NewReader(truncated), int64(len(truncated)))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	if _, err := ra.ReadAt(make([]byte, 10), int64(gcmChunkSize)+5); err == nil {
+		t.Error("Expected error reading a truncated stream")
+	}
+
+	tampered := append([]byte(nil), encrypted...)
+	tampered[gcmHeaderSize+chunkLen+10] ^= 0x01
+	ra, _ = cipher.(RandomAccessCipher).NewReaderAt(bytes.NewReader(tampered), int64(len(tampered)))
+	if _, err := ra.ReadAt(make([]byte, 10), 0); err != nil {
+		t.Errorf("Unexpected error reading an untouched chunk: %v", err)
+	}
+	if _, err := ra.ReadAt(make([]byte, 10), int64(gcmChunkSize)); err == nil {
+		t.Error("Expected error reading a tampered chunk")
+	}
+}
+
+func TestEnvelopeReaderAt(t *testing.T) {
+	provider, _ := NewMemoryKeyProvider()
+	cipher, _ := NewEnvelopeCipher(provider)
+
+	plaintext := make([]byte, 2*gcmChunkSize+10)
+	rand.Read(plaintext)
+	encrypted := encryptForTest(t, cipher, plaintext)
+
+	ra, err := cipher.(RandomAccessCipher).NewReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	if ra.Size() != int64(len(plaintext)) {
+		t.Fatalf("Expected plaintext size %d, got %d", len(plaintext), ra.Size())
+	}
+
+	buf := make([]byte, 20)
+	if _, err := ra.ReadAt(buf, int64(gcmChunkSize)+3); err != nil {
+		t.Fatalf("ReadAt failed: %v", err)
+	}
+	if !bytes.Equal(buf, plaintext[gcmChunkSize+3:gcmChunkSize+23]) {
+		t.Error("Decrypted content doesn't match original")
+	}
+}
+
+func TestCBCIsNotRandomAccess(t *testing.T) {
+	cipher, _ := NewCipher(AES256CBC, make([]byte, 32), make([]byte, 16))
+	if _, ok := cipher.(RandomAccessCipher); ok {
+		t.Error("CBC cipher should not support random access")
+	}
+}
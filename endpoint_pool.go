@@ -0,0 +1,248 @@
+package walrus_go
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultFailureThreshold is the number of consecutive failures that marks an endpoint unhealthy
+	defaultFailureThreshold = 3
+
+	// defaultCooldown is how long an unhealthy endpoint is tried last before it gets another chance
+	defaultCooldown = 30 * time.Second
+
+	// latencySmoothing is the weight of the newest sample in the latency moving average
+	latencySmoothing = 0.3
+)
+
+// EndpointStats is a snapshot of the health of an aggregator or publisher endpoint
+type EndpointStats struct {
+	// URL is the base URL of the endpoint
+	URL string
+	// Successes and Failures count the attempts made against the endpoint
+	Successes int64
+	Failures  int64
+	// ConsecutiveFailures counts the failures since the last success
+	ConsecutiveFailures int
+	// Latency is a moving average of the time to response headers of successful attempts
+	Latency time.Duration
+	// Healthy is false while the endpoint's circuit breaker is open
+	Healthy bool
+	// UnhealthyUntil is when an unhealthy endpoint will be tried again
+	UnhealthyUntil time.Time
+	// LastError describes the most recent failure, if any
+	LastError string
+	// LastProbe is when the health checker last probed the endpoint
+	LastProbe time.Time
+	// Version is the API version reported by the endpoint's last successful probe
+	Version string
+}
+
+// SuccessRate returns the fraction of successful attempts, or 1 if the endpoint has not been used
+func (s EndpointStats) SuccessRate() float64 {
+	total := s.Successes + s.Failures
+	if total == 0 {
+		return 1
+	}
+	return float64(s.Successes) / float64(total)
+}
+
+// endpointPool tracks the health of endpoints and decides the order in which they are tried.
+// Endpoints that fail failureThreshold times in a row are moved to the back for cooldown
+// (an open circuit breaker); once the cooldown passes they are tried again, and a single
+// further failure opens the breaker again. Endpoints that fail a health check probe stay at
+// the back until a probe or a request succeeds.
+type endpointPool struct {
+	mu               sync.Mutex
+	endpoints        map[string]*endpointState
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time
+}
+
+// endpointState is the pool's record of one endpoint
+type endpointState struct {
+	stats EndpointStats
+	// pruned is set while the endpoint fails health check probes
+	pruned bool
+}
+
+// healthy reports whether the endpoint should be tried before unhealthy ones
+func (s *endpointState) healthy(now time.Time) bool {
+	return !s.pruned && !now.Before(s.stats.UnhealthyUntil)
+}
+
+func newEndpointPool() *endpointPool {
+	return &endpointPool{
+		endpoints:        make(map[string]*endpointState),
+		failureThreshold: defaultFailureThreshold,
+		cooldown:         defaultCooldown,
+		now:              time.Now,
+	}
+}
+
+// WithCircuitBreaker sets after how many consecutive failures an endpoint is considered
+// unhealthy and how long it is tried last before it gets another chance
+func WithCircuitBreaker(failureThreshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		if failureThreshold > 0 {
+			c.endpoints.failureThreshold = failureThreshold
+		}
+		if cooldown >= 0 {
+			c.endpoints.cooldown = cooldown
+		}
+	}
+}
+
+// order returns urls sorted for the next request: healthy endpoints before unhealthy ones,
+// then by score. Endpoints without samples get the average score of the others, and ties
+// keep the configured order.
+func (p *endpointPool) order(urls []string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	healthy := make(map[string]bool, len(urls))
+	scores := make(map[string]float64, len(urls))
+
+	var total float64
+	var known int
+	for _, u := range urls {
+		s, ok := p.endpoints[u]
+		healthy[u] = !ok || s.healthy(now)
+		if ok && s.stats.Latency > 0 {
+			scores[u] = score(&s.stats)
+			total += scores[u]
+			known++
+		}
+	}
+	for _, u := range urls {
+		if _, ok := scores[u]; !ok && known > 0 {
+			scores[u] = total / float64(known)
+		}
+	}
+
+	ordered := append([]string(nil), urls...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if healthy[a] != healthy[b] {
+			return healthy[a]
+		}
+		return scores[a] < scores[b]
+	})
+	return ordered
+}
+
+// score ranks an endpoint by its latency penalized by its failure rate; lower is better
+func score(s *EndpointStats) float64 {
+	// Laplace smoothing keeps a single early failure from dominating
+	rate := float64(s.Successes+1) / float64(s.Successes+s.Failures+2)
+	return float64(s.Latency) / rate
+}
+
+// recordSuccess records a successful attempt and closes the endpoint's circuit breaker
+func (p *endpointPool) recordSuccess(url string, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.get(url)
+	state.pruned = false
+	s := &state.stats
+	s.Successes++
+	s.ConsecutiveFailures = 0
+	s.UnhealthyUntil = time.Time{}
+	s.Latency = smoothLatency(s.Latency, latency)
+}
+
+// smoothLatency adds a latency sample to a moving average
+func smoothLatency(average, sample time.Duration) time.Duration {
+	if average == 0 {
+		return sample
+	}
+	return time.Duration(latencySmoothing*float64(sample) + (1-latencySmoothing)*float64(average))
+}
+
+// recordFailure records a failed attempt and opens the endpoint's circuit breaker once it
+// has failed failureThreshold times in a row
+func (p *endpointPool) recordFailure(url string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := &p.get(url).stats
+	s.Failures++
+	s.ConsecutiveFailures++
+	if err != nil {
+		s.LastError = err.Error()
+	}
+	if s.ConsecutiveFailures >= p.failureThreshold {
+		s.UnhealthyUntil = p.now().Add(p.cooldown)
+	}
+}
+
+// recordProbe records the outcome of a health check probe. A failed probe prunes the endpoint
+// until a later probe or request succeeds; a successful one restores it.
+func (p *endpointPool) recordProbe(url string, latency time.Duration, version string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.get(url)
+	s := &state.stats
+	s.LastProbe = p.now()
+	if err != nil {
+		state.pruned = true
+		s.LastError = err.Error()
+		return
+	}
+
+	state.pruned = false
+	s.ConsecutiveFailures = 0
+	s.UnhealthyUntil = time.Time{}
+	s.Latency = smoothLatency(s.Latency, latency)
+	if version != "" {
+		s.Version = version
+	}
+}
+
+// get returns the state of url, creating it if needed. p.mu must be held.
+func (p *endpointPool) get(url string) *endpointState {
+	s, ok := p.endpoints[url]
+	if !ok {
+		s = &endpointState{stats: EndpointStats{URL: url}}
+		p.endpoints[url] = s
+	}
+	return s
+}
+
+// snapshot returns a copy of the stats of urls, in order and without duplicates
+func (p *endpointPool) snapshot(urls []string) []EndpointStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	seen := make(map[string]bool, len(urls))
+	stats := make([]EndpointStats, 0, len(urls))
+	for _, u := range urls {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+
+		s := EndpointStats{URL: u, Healthy: true}
+		if state, ok := p.endpoints[u]; ok {
+			s = state.stats
+			s.Healthy = state.healthy(now)
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// EndpointStats returns a snapshot of the health of the configured aggregator and publisher endpoints
+func (c *Client) EndpointStats() []EndpointStats {
+	urls := make([]string, 0, len(c.AggregatorURL)+len(c.PublisherURL))
+	urls = append(urls, c.AggregatorURL...)
+	urls = append(urls, c.PublisherURL...)
+	return c.endpoints.snapshot(urls)
+}
@@ -0,0 +1,124 @@
+package walrus_go
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEndpointCircuitBreaker tests that a failing endpoint is skipped until its cooldown passes
+func TestEndpointCircuitBreaker(t *testing.T) {
+	var badHits int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&badHits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer good.Close()
+
+	client := NewClient(
+		WithRetryConfig(1, time.Millisecond),
+		WithAggregatorURLs([]string{bad.URL, good.URL}),
+		WithCircuitBreaker(2, time.Hour),
+	)
+
+	// The first two reads hit the bad endpoint first and open its breaker
+	for i := 0; i < 5; i++ {
+		if _, err := client.Read("test-blob", nil); err != nil {
+			t.Fatalf("Read %d failed: %v", i, err)
+		}
+	}
+	if got := atomic.LoadInt32(&badHits); got != 2 {
+		t.Errorf("Expected the bad endpoint to be tried 2 times, got %d", got)
+	}
+
+	stats := client.EndpointStats()
+	if len(stats) != 2+len(DefaultTestnetPublishers) {
+		t.Fatalf("Expected stats for all endpoints, got %d", len(stats))
+	}
+	if stats[0].URL != bad.URL || stats[0].Healthy || stats[0].Failures != 2 || stats[0].LastError == "" {
+		t.Errorf("Unexpected stats for the bad endpoint: %+v", stats[0])
+	}
+	if stats[1].URL != good.URL || !stats[1].Healthy || stats[1].Successes != 5 || stats[1].Latency <= 0 {
+		t.Errorf("Unexpected stats for the good endpoint: %+v", stats[1])
+	}
+	if stats[1].SuccessRate() != 1 {
+		t.Errorf("Expected success rate 1, got %v", stats[1].SuccessRate())
+	}
+}
+
+// TestEndpointPoolOrder tests ordering by health and latency
+func TestEndpointPoolOrder(t *testing.T) {
+	now := time.Now()
+	pool := newEndpointPool()
+	pool.now = func() time.Time { return now }
+
+	urls := []string{"a", "b", "c", "d"}
+	if got := pool.order(urls); !equalStrings(got, urls) {
+		t.Errorf("Expected configured order without samples, got %v", got)
+	}
+
+	pool.recordSuccess("b", 10*time.Millisecond)
+	pool.recordSuccess("c", 300*time.Millisecond)
+	for i := 0; i < defaultFailureThreshold; i++ {
+		pool.recordFailure("a", errors.New("connection refused"))
+	}
+
+	// d has no samples and gets the average score, which ranks it between b and c
+	if got := pool.order(urls); !equalStrings(got, []string{"b", "d", "c", "a"}) {
+		t.Errorf("Unexpected order: %v", got)
+	}
+
+	// After the cooldown a is tried again, and a single failure opens the breaker again
+	now = now.Add(defaultCooldown)
+	if got := pool.order(urls); got[3] == "a" {
+		t.Errorf("Expected a to be tried again after cooldown, got %v", got)
+	}
+	pool.recordFailure("a", errors.New("connection refused"))
+	if got := pool.order(urls); got[3] != "a" {
+		t.Errorf("Expected a to be last after failing again, got %v", got)
+	}
+
+	// A success closes the breaker
+	pool.recordSuccess("a", time.Millisecond)
+	if got := pool.order(urls); got[0] != "a" {
+		t.Errorf("Expected a to be first after a fast success, got %v", got)
+	}
+}
+
+// TestEndpointClientErrorsNotCounted tests that client errors don't count against endpoint health
+func TestEndpointClientErrorsNotCounted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithAggregatorURLs([]string{server.URL}))
+	for i := 0; i < defaultFailureThreshold; i++ {
+		client.Read("missing-blob", nil)
+	}
+
+	stats := client.EndpointStats()[0]
+	if !stats.Healthy || stats.Failures != 0 {
+		t.Errorf("Expected a healthy endpoint without failures, got %+v", stats)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
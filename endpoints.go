@@ -75,3 +75,7 @@ var DefaultTestnetAggregators = []string{
 	"https://aggregator.walrus.banansen.dev",
 	"https://aggregator.walrus.silentvalidator.com",
 }
+
+var DefaultMainnetAggregators = []string{
+	"https://aggregator.walrus-mainnet.walrus.space",
+}
@@ -0,0 +1,116 @@
+package walrus_go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUploadTooLarge is returned when an upload of unknown length exceeds
+// Client.MaxUnknownLengthUploadSize
+var ErrUploadTooLarge = errors.New("upload too large")
+
+// Sentinel errors matched by APIError, for use with errors.Is
+var (
+	// ErrBlobNotFound means the aggregator does not know the requested blob
+	ErrBlobNotFound = errors.New("blob not found")
+
+	// ErrPayloadTooLarge means the publisher rejected the upload because of its size
+	ErrPayloadTooLarge = errors.New("payload too large")
+
+	// ErrInsufficientFunds means the publisher could not pay for storing the blob
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrUnauthorized means the endpoint rejected the credentials of the request, or that it
+	// requires credentials and none were sent
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrTransport means the request failed before an HTTP response was received
+	ErrTransport = errors.New("transport error")
+)
+
+// APIError describes one failed attempt of a request to a publisher or aggregator
+type APIError struct {
+	// StatusCode is the HTTP status code, or 0 if no response was received
+	StatusCode int
+	// URL is the full URL of the attempt
+	URL string
+	// Body is the response body, typically the error message of the endpoint
+	Body string
+	// Attempt is the 1-based number of the attempt within the request
+	Attempt int
+	// RetryAfter is the delay requested by the endpoint's Retry-After header, if any
+	RetryAfter time.Duration
+	// Err is the underlying error for transport failures and invalid responses
+	Err error
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.StatusCode == 0:
+		return fmt.Sprintf("request to %s failed: %v", e.URL, e.Err)
+	case e.Err != nil:
+		return fmt.Sprintf("request to %s returned an invalid response: %v", e.URL, e.Err)
+	case e.Body != "":
+		return fmt.Sprintf("request to %s failed with status code %d: %s", e.URL, e.StatusCode, e.Body)
+	default:
+		return fmt.Sprintf("request to %s failed with status code %d", e.URL, e.StatusCode)
+	}
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the sentinel errors of this package
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBlobNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrPayloadTooLarge:
+		return e.StatusCode == http.StatusRequestEntityTooLarge
+	case ErrInsufficientFunds:
+		body := strings.ToLower(e.Body)
+		return e.StatusCode >= 400 && (strings.Contains(body, "insufficient") || strings.Contains(body, "sufficient balance"))
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrTransport:
+		return e.StatusCode == 0
+	}
+	return false
+}
+
+// MultiError collects the errors of every attempt of a request, in order. errors.Is and
+// errors.As match if any of the attempts matches.
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	if len(e.Errors) == 0 {
+		return "all retry attempts failed"
+	}
+	return fmt.Sprintf("all retry attempts failed (%d attempts): %v", len(e.Errors), e.Errors[len(e.Errors)-1])
+}
+
+// Is reports whether any attempt's error matches target
+func (e *MultiError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first attempt's error that matches target
+func (e *MultiError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
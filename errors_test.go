@@ -0,0 +1,108 @@
+package walrus_go
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAPIErrorSentinels tests that failed requests can be classified with errors.Is
+func TestAPIErrorSentinels(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		sentinel error
+	}{
+		{name: "not found", status: http.StatusNotFound, body: "blob not found", sentinel: ErrBlobNotFound},
+		{name: "payload too large", status: http.StatusRequestEntityTooLarge, sentinel: ErrPayloadTooLarge},
+		{name: "insufficient funds", status: http.StatusInternalServerError, body: "could not find WAL coins with sufficient balance", sentinel: ErrInsufficientFunds},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewClient(
+				WithRetryConfig(0, 0),
+				WithAggregatorURLs([]string{server.URL}),
+			)
+
+			_, err := client.Read("test-blob", nil)
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("Expected error matching %v, got: %v", tt.sentinel, err)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected an *APIError, got: %T", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("Expected status code %d, got %d", tt.status, apiErr.StatusCode)
+			}
+			if apiErr.URL != server.URL+"/v1/blobs/test-blob" {
+				t.Errorf("Expected URL %s/v1/blobs/test-blob, got %s", server.URL, apiErr.URL)
+			}
+			if apiErr.Body != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, apiErr.Body)
+			}
+			if apiErr.Attempt != 1 {
+				t.Errorf("Expected attempt 1, got %d", apiErr.Attempt)
+			}
+		})
+	}
+}
+
+// TestMultiError tests that every per-endpoint failure is reported
+func TestMultiError(t *testing.T) {
+	server1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server1.Close()
+
+	// A closed server produces a transport error
+	server2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server2.Close()
+
+	client := NewClient(
+		WithRetryConfig(1, 10*time.Millisecond),
+		WithAggregatorURLs([]string{server1.URL, server2.URL}),
+	)
+
+	_, err := client.Read("test-blob", nil)
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("Expected a *MultiError, got: %T %v", err, err)
+	}
+	if len(multiErr.Errors) != 2 {
+		t.Fatalf("Expected 2 attempt errors, got %d", len(multiErr.Errors))
+	}
+
+	var first, second *APIError
+	if !errors.As(multiErr.Errors[0], &first) || !errors.As(multiErr.Errors[1], &second) {
+		t.Fatal("Expected every attempt error to be an *APIError")
+	}
+	if first.StatusCode != http.StatusServiceUnavailable || !strings.HasPrefix(first.URL, server1.URL) || first.Attempt != 1 {
+		t.Errorf("Unexpected first attempt error: %+v", first)
+	}
+	if second.StatusCode != 0 || !strings.HasPrefix(second.URL, server2.URL) || second.Attempt != 2 {
+		t.Errorf("Unexpected second attempt error: %+v", second)
+	}
+
+	if !errors.Is(err, ErrTransport) {
+		t.Error("Expected the error to match ErrTransport")
+	}
+	if errors.Is(err, ErrBlobNotFound) {
+		t.Error("Expected the error not to match ErrBlobNotFound")
+	}
+	if !strings.Contains(err.Error(), "all retry attempts failed") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package walrus_go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultHealthCheckInterval is used by StartHealthCheck when no interval is given
+	defaultHealthCheckInterval = time.Minute
+
+	// healthCheckTimeout bounds each probe
+	healthCheckTimeout = 5 * time.Second
+
+	// maxProbeBodySize limits how much of the API spec is read to find the version
+	maxProbeBodySize = 1024 * 1024
+)
+
+// healthChecker is the state of a running background health check
+type healthChecker struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartHealthCheck starts probing every configured aggregator and publisher in the background,
+// once right away and then every interval (one minute if interval is not positive). Endpoints
+// that fail a probe are tried last until a later probe or request succeeds. It returns an error
+// if a health check is already running.
+func (c *Client) StartHealthCheck(interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	if c.health != nil {
+		return fmt.Errorf("health check is already running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &healthChecker{cancel: cancel, done: make(chan struct{})}
+	c.health = h
+
+	go func() {
+		defer close(h.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			c.CheckEndpoints(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// StopHealthCheck stops the background health check and waits for a running probe round to
+// finish. It does nothing if no health check is running.
+func (c *Client) StopHealthCheck() {
+	c.healthMu.Lock()
+	h := c.health
+	c.health = nil
+	c.healthMu.Unlock()
+
+	if h != nil {
+		h.cancel()
+		<-h.done
+	}
+}
+
+// CheckEndpoints probes every configured aggregator and publisher once, concurrently, and
+// returns the resulting endpoint stats. Each probe is a GET /v1/api request.
+func (c *Client) CheckEndpoints(ctx context.Context) []EndpointStats {
+	urls := make([]string, 0, len(c.AggregatorURL)+len(c.PublisherURL))
+	urls = append(urls, c.AggregatorURL...)
+	urls = append(urls, c.PublisherURL...)
+
+	seen := make(map[string]bool, len(urls))
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+
+		wg.Add(1)
+		go func(baseURL string) {
+			defer wg.Done()
+			c.probeEndpoint(ctx, baseURL)
+		}(u)
+	}
+	wg.Wait()
+
+	return c.endpoints.snapshot(urls)
+}
+
+// probeEndpoint requests the API spec of baseURL and records the outcome in the endpoint pool
+func (c *Client) probeEndpoint(ctx context.Context, baseURL string) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/v1/api", nil)
+	if err != nil {
+		c.endpoints.recordProbe(baseURL, 0, "", err)
+		return
+	}
+
+	start := time.Now()
+	resp, err := c.send(req, baseURL)
+	if err != nil {
+		// A probe aborted by StopHealthCheck or failing to authenticate says nothing about the endpoint
+		var authErr *authError
+		if ctx.Err() == context.Canceled || errors.As(err, &authErr) {
+			return
+		}
+		c.endpoints.recordProbe(baseURL, 0, "", err)
+		return
+	}
+	defer resp.Body.Close()
+	latency := time.Since(start)
+
+	if resp.StatusCode != http.StatusOK {
+		c.endpoints.recordProbe(baseURL, 0, "", &APIError{StatusCode: resp.StatusCode, URL: req.URL.String()})
+		return
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	c.endpoints.recordProbe(baseURL, latency, parseAPIVersion(body), nil)
+}
+
+// parseAPIVersion extracts info.version from an OpenAPI document, or returns "" if the
+// document is not JSON or has no version
+func parseAPIVersion(spec []byte) string {
+	var doc struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return ""
+	}
+	return doc.Info.Version
+}
@@ -0,0 +1,120 @@
+package walrus_go
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCheckEndpoints tests that probes prune unreachable endpoints and restore recovered ones
+func TestCheckEndpoints(t *testing.T) {
+	var down int32 = 1
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"openapi":"3.0.3","info":{"title":"Walrus Aggregator","version":"1.20.0"}}`))
+	}))
+	defer flaky.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/api" {
+			t.Errorf("Unexpected probe path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"info":{"version":"1.19.2"}}`))
+	}))
+	defer healthy.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	client := NewClient(
+		WithAggregatorURLs([]string{flaky.URL, healthy.URL}),
+		WithPublisherURLs([]string{closedURL, healthy.URL}),
+	)
+
+	stats := client.CheckEndpoints(context.Background())
+	if len(stats) != 3 {
+		t.Fatalf("Expected stats for 3 distinct endpoints, got %d", len(stats))
+	}
+	byURL := make(map[string]EndpointStats)
+	for _, s := range stats {
+		byURL[s.URL] = s
+		if s.LastProbe.IsZero() {
+			t.Errorf("Expected %s to be probed", s.URL)
+		}
+	}
+	if s := byURL[flaky.URL]; s.Healthy || s.LastError == "" {
+		t.Errorf("Expected the failing endpoint to be pruned, got %+v", s)
+	}
+	if s := byURL[closedURL]; s.Healthy {
+		t.Errorf("Expected the unreachable endpoint to be pruned, got %+v", s)
+	}
+	if s := byURL[healthy.URL]; !s.Healthy || s.Version != "1.19.2" || s.Latency <= 0 {
+		t.Errorf("Unexpected stats for the healthy endpoint: %+v", s)
+	}
+
+	// Pruned endpoints are tried last
+	if got := client.endpoints.order(client.AggregatorURL); got[0] != healthy.URL {
+		t.Errorf("Expected the healthy endpoint first, got %v", got)
+	}
+
+	atomic.StoreInt32(&down, 0)
+	client.CheckEndpoints(context.Background())
+	if s := client.EndpointStats()[0]; !s.Healthy || s.Version != "1.20.0" {
+		t.Errorf("Expected the recovered endpoint to be restored, got %+v", s)
+	}
+}
+
+// TestStartStopHealthCheck tests the background health check lifecycle
+func TestStartStopHealthCheck(t *testing.T) {
+	var probes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		w.Write([]byte("<html>API</html>"))
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithAggregatorURLs([]string{server.URL}),
+		WithPublisherURLs([]string{server.URL}),
+	)
+
+	if err := client.StartHealthCheck(10 * time.Millisecond); err != nil {
+		t.Fatalf("StartHealthCheck failed: %v", err)
+	}
+	if err := client.StartHealthCheck(10 * time.Millisecond); err == nil {
+		t.Error("Expected an error when starting a second health check")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&probes) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for probes")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client.StopHealthCheck()
+	stopped := atomic.LoadInt32(&probes)
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&probes); got != stopped {
+		t.Errorf("Expected no probes after StopHealthCheck, got %d more", got-stopped)
+	}
+
+	if s := client.EndpointStats()[0]; !s.Healthy || s.Version != "" {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+
+	// The health check can be started again after it was stopped
+	if err := client.StartHealthCheck(time.Hour); err != nil {
+		t.Errorf("Expected restart to succeed, got: %v", err)
+	}
+	client.StopHealthCheck()
+	client.StopHealthCheck()
+}
@@ -0,0 +1,195 @@
+package walrus_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WithHedgedReads enables hedged reads for Read, ReadToFile, ReadToReader and Head. A read is
+// first sent to the best aggregator; if it has not completed after delay, it is also sent to
+// the next one, and so on until up to extra additional requests are in flight. The first
+// successful response wins and the others are cancelled. A failed request is replaced by one
+// to the next aggregator right away. An extra of zero or less disables hedging.
+func WithHedgedReads(delay time.Duration, extra int) ClientOption {
+	return func(c *Client) {
+		c.hedgeDelay = delay
+		c.hedgeExtra = extra
+	}
+}
+
+// doRead sends a read request to the aggregators, hedged if enabled
+func (c *Client) doRead(ctx context.Context, req *http.Request) (*http.Response, error) {
+	resp, _, err := c.doReadFrom(ctx, req, c.AggregatorURL)
+	return resp, err
+}
+
+// doReadFrom sends a read request to the given aggregators, hedged if enabled, and returns the
+// base URL of the aggregator that served the response along with it
+func (c *Client) doReadFrom(ctx context.Context, req *http.Request, urls []string) (*http.Response, string, error) {
+	if c.hedgeExtra > 0 {
+		return c.doHedged(ctx, req, urls)
+	}
+	return c.doWithRetryFrom(ctx, req, urls)
+}
+
+// hedgeResult is the outcome of one hedged attempt
+type hedgeResult struct {
+	attempt int
+	baseURL string
+	resp    *http.Response
+	err     *APIError
+}
+
+// doHedged sends a bodiless request to up to 1+hedgeExtra endpoints at a time, launching the
+// next endpoint every hedgeDelay or as soon as an attempt fails, and returns the first usable
+// response. Each endpoint is tried at most once. After a non-retryable error no further
+// attempts are launched, but attempts already in flight may still succeed. A failure of the
+// AuthProvider ends the request at once and is returned as is.
+// It returns the base URL of the endpoint that served the response along with it. If the
+// request fails, the returned error is a *MultiError holding an *APIError per attempt.
+func (c *Client) doHedged(ctx context.Context, req *http.Request, urls []string) (*http.Response, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", fmt.Errorf("request canceled: %w", err)
+	}
+	if len(urls) == 0 {
+		return nil, "", fmt.Errorf("no endpoints configured")
+	}
+
+	ordered := c.endpoints.order(urls)
+	results := make(chan hedgeResult, len(ordered))
+	cancels := make([]context.CancelFunc, 0, len(ordered))
+	next, inFlight := 0, 0
+
+	launch := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		baseURL := ordered[next]
+		next++
+		inFlight++
+		go func(attempt int) {
+			results <- c.hedgedAttempt(attemptCtx, req, baseURL, attempt)
+		}(next)
+	}
+
+	// abandon cancels all attempts except the winner, if any, and releases the responses of
+	// those still in flight
+	abandon := func(pending, winner int) {
+		for i, cancel := range cancels {
+			if i+1 != winner {
+				cancel()
+			}
+		}
+		go func() {
+			for ; pending > 0; pending-- {
+				if r := <-results; r.resp != nil {
+					r.resp.Body.Close()
+				}
+			}
+		}()
+	}
+
+	timer := time.NewTimer(c.hedgeDelay)
+	defer timer.Stop()
+	launch()
+
+	var attemptErrs []error
+	stopped := false
+	for inFlight > 0 {
+		select {
+		case <-ctx.Done():
+			abandon(inFlight, 0)
+			return nil, "", fmt.Errorf("request canceled: %w", ctx.Err())
+
+		case <-timer.C:
+			if !stopped && next < len(ordered) && inFlight <= c.hedgeExtra {
+				launch()
+				timer.Reset(c.hedgeDelay)
+			}
+
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				// Keep the winner's context alive until its body is closed
+				resp := r.resp
+				resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancels[r.attempt-1]}
+				abandon(inFlight, r.attempt)
+				return resp, r.baseURL, nil
+			}
+
+			cancels[r.attempt-1]()
+			var authErr *authError
+			if errors.As(r.err, &authErr) {
+				abandon(inFlight, 0)
+				return nil, "", authErr
+			}
+			attemptErrs = append(attemptErrs, r.err)
+			if !IsRetryable(r.err) {
+				stopped = true
+			}
+			if !stopped && next < len(ordered) {
+				launch()
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(c.hedgeDelay)
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, "", fmt.Errorf("request canceled: %w", err)
+	}
+	return nil, "", &MultiError{Errors: attemptErrs}
+}
+
+// hedgedAttempt sends one attempt of a hedged request and records its outcome in the endpoint pool
+func (c *Client) hedgedAttempt(ctx context.Context, req *http.Request, baseURL string, attempt int) hedgeResult {
+	fullURL, err := url.Parse(baseURL + req.URL.String())
+	if err != nil {
+		return hedgeResult{attempt: attempt, baseURL: baseURL, err: &APIError{URL: baseURL, Attempt: attempt, Err: err}}
+	}
+
+	newReq := req.Clone(ctx)
+	newReq.URL = fullURL
+	newReq.Host = ""
+
+	start := time.Now()
+	resp, err := c.send(newReq, baseURL)
+	var authErr *authError
+	if errors.As(err, &authErr) {
+		return hedgeResult{attempt: attempt, baseURL: baseURL, err: &APIError{URL: fullURL.String(), Attempt: attempt, Err: authErr}}
+	}
+	attemptErr := checkResponse(newReq, resp, err)
+	if attemptErr == nil {
+		c.endpoints.recordSuccess(baseURL, time.Since(start))
+		return hedgeResult{attempt: attempt, baseURL: baseURL, resp: resp}
+	}
+	attemptErr.URL = fullURL.String()
+	attemptErr.Attempt = attempt
+
+	// Attempts cancelled because another one won say nothing about the endpoint
+	if ctx.Err() == nil && IsRetryable(attemptErr) {
+		c.endpoints.recordFailure(baseURL, attemptErr)
+	}
+	return hedgeResult{attempt: attempt, baseURL: baseURL, err: attemptErr}
+}
+
+// cancelOnClose cancels the context of a response when its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
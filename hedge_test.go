@@ -0,0 +1,117 @@
+package walrus_go
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHedgedRead tests that a slow aggregator is overtaken by a hedged request to the next one
+func TestHedgedRead(t *testing.T) {
+	slowCancelled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(slowCancelled)
+		case <-time.After(5 * time.Second):
+			w.Write([]byte("slow"))
+		}
+	}))
+	defer slow.Close()
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fast"))
+	}))
+	defer fast.Close()
+
+	client := NewClient(
+		WithAggregatorURLs([]string{slow.URL, fast.URL}),
+		WithHedgedReads(20*time.Millisecond, 1),
+	)
+
+	start := time.Now()
+	data, err := client.Read("test-blob", nil)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != "fast" {
+		t.Errorf("Expected data from the fast aggregator, got %q", data)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the hedged request to win quickly, took %v", elapsed)
+	}
+
+	select {
+	case <-slowCancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the slow request to be cancelled")
+	}
+}
+
+// TestHedgedReadFailover tests that a failed attempt immediately launches the next one and
+// that the winning response can still be streamed after it was returned
+func TestHedgedReadFailover(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	content := strings.Repeat("walrus", 100000)
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer working.Close()
+
+	client := NewClient(
+		WithAggregatorURLs([]string{failing.URL, working.URL}),
+		WithHedgedReads(time.Hour, 1),
+	)
+
+	reader, err := client.ReadToReader("test-blob", nil)
+	if err != nil {
+		t.Fatalf("ReadToReader failed: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("Failed to read stream: %v", err)
+	}
+	if string(data) != content {
+		t.Errorf("Content mismatch: got %d bytes, expected %d", len(data), len(content))
+	}
+
+	if stats := client.EndpointStats(); stats[0].Failures != 1 || stats[1].Successes != 1 {
+		t.Errorf("Unexpected endpoint stats: %+v", stats[:2])
+	}
+}
+
+// TestHedgedReadNotFound tests that a non-retryable error stops launching further attempts
+func TestHedgedReadNotFound(t *testing.T) {
+	var attempts int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	server1 := httptest.NewServer(handler)
+	defer server1.Close()
+	server2 := httptest.NewServer(handler)
+	defer server2.Close()
+
+	client := NewClient(
+		WithAggregatorURLs([]string{server1.URL, server2.URL}),
+		WithHedgedReads(time.Hour, 1),
+	)
+
+	_, err := client.Head("missing-blob")
+	if !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("Expected ErrBlobNotFound, got: %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
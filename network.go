@@ -0,0 +1,246 @@
+package walrus_go
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Network is a Walrus deployment: the aggregators and publishers to use and the number of
+// epochs blobs are stored for when StoreOptions does not set one
+type Network struct {
+	Name          string   `json:"name"`
+	Aggregators   []string `json:"aggregators"`
+	Publishers    []string `json:"publishers"`
+	DefaultEpochs int      `json:"default_epochs"`
+}
+
+var (
+	// Testnet uses the public testnet aggregators and publishers
+	Testnet = Network{
+		Name:          "testnet",
+		Aggregators:   DefaultTestnetAggregators,
+		Publishers:    DefaultTestnetPublishers,
+		DefaultEpochs: 1,
+	}
+
+	// Mainnet uses the public mainnet aggregators. Storing on mainnet costs WAL and SUI, so there
+	// are no public publishers; add your own with WithPublisherURLs.
+	Mainnet = Network{
+		Name:          "mainnet",
+		Aggregators:   DefaultMainnetAggregators,
+		DefaultEpochs: 1,
+	}
+
+	// Local uses a walrus daemon running on this machine with its default address
+	Local = Network{
+		Name:          "local",
+		Aggregators:   []string{"http://127.0.0.1:31415"},
+		Publishers:    []string{"http://127.0.0.1:31415"},
+		DefaultEpochs: 1,
+	}
+)
+
+// LookupNetwork returns the built-in network with the given name
+func LookupNetwork(name string) (Network, bool) {
+	for _, n := range []Network{Testnet, Mainnet, Local} {
+		if strings.EqualFold(n.Name, name) {
+			return n, true
+		}
+	}
+	return Network{}, false
+}
+
+// WithNetwork configures the client for network, replacing the aggregator and publisher URLs
+func WithNetwork(network Network) ClientOption {
+	return func(c *Client) {
+		c.AggregatorURL = append([]string(nil), network.Aggregators...)
+		c.PublisherURL = append([]string(nil), network.Publishers...)
+		c.defaultEpochs = network.DefaultEpochs
+	}
+}
+
+// LoadNetwork reads a network profile from a JSON or YAML file. The format is chosen by the
+// file extension, and files with other extensions are tried as JSON first. A YAML profile
+// looks like this:
+//
+//	name: staging
+//	default_epochs: 5
+//	aggregators:
+//	  - https://aggregator.staging.example.com
+//	publishers:
+//	  - https://publisher.staging.example.com
+func LoadNetwork(path string) (*Network, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read network file: %w", err)
+	}
+
+	var network *Network
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		network, err = parseNetworkJSON(data)
+	case ".yaml", ".yml":
+		network, err = parseNetworkYAML(data)
+	default:
+		if network, err = parseNetworkJSON(data); err != nil {
+			network, err = parseNetworkYAML(data)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid network file %s: %w", path, err)
+	}
+
+	if err := network.validate(); err != nil {
+		return nil, fmt.Errorf("invalid network file %s: %w", path, err)
+	}
+	return network, nil
+}
+
+// validate checks the endpoint URLs and strips trailing slashes, since request paths are
+// appended to them
+func (n *Network) validate() error {
+	if len(n.Aggregators) == 0 && len(n.Publishers) == 0 {
+		return fmt.Errorf("network has no aggregators or publishers")
+	}
+	if n.DefaultEpochs < 0 {
+		return fmt.Errorf("default_epochs cannot be negative")
+	}
+
+	for _, urls := range [][]string{n.Aggregators, n.Publishers} {
+		for i, u := range urls {
+			parsed, err := url.Parse(u)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("invalid endpoint URL: %q", u)
+			}
+			urls[i] = strings.TrimRight(u, "/")
+		}
+	}
+	return nil
+}
+
+func parseNetworkJSON(data []byte) (*Network, error) {
+	var network Network
+	if err := json.Unmarshal(data, &network); err != nil {
+		return nil, err
+	}
+	return &network, nil
+}
+
+// parseNetworkYAML parses the subset of YAML needed for network profiles: top-level scalar
+// keys, block lists ("- item") and flow lists ("[a, b]"), with comments and quoted strings
+func parseNetworkYAML(data []byte) (*Network, error) {
+	var network Network
+	var list *[]string
+
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		text := strings.TrimRight(stripYAMLComment(line), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if list == nil {
+				return nil, fmt.Errorf("line %d: list item outside of a list", lineNo)
+			}
+			*list = append(*list, unquoteYAML(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			continue
+		}
+
+		if text[0] == ' ' || text[0] == '\t' {
+			return nil, fmt.Errorf("line %d: unexpected indentation", lineNo)
+		}
+		list = nil
+
+		colon := strings.Index(text, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: expected key: value", lineNo)
+		}
+		key := strings.TrimSpace(text[:colon])
+		value := strings.TrimSpace(text[colon+1:])
+
+		switch key {
+		case "name":
+			network.Name = unquoteYAML(value)
+		case "default_epochs":
+			epochs, err := strconv.Atoi(unquoteYAML(value))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid default_epochs: %q", lineNo, value)
+			}
+			network.DefaultEpochs = epochs
+		case "aggregators", "publishers":
+			target := &network.Aggregators
+			if key == "publishers" {
+				target = &network.Publishers
+			}
+			if value == "" {
+				list = target
+				continue
+			}
+			items, err := parseYAMLFlowList(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			*target = items
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q", lineNo, key)
+		}
+	}
+	return &network, nil
+}
+
+// parseYAMLFlowList parses a list written as [a, b, c]
+func parseYAMLFlowList(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("expected a list, got %q", value)
+	}
+	inner := strings.TrimSpace(value[1 : len(value)-1])
+	if inner == "" {
+		return nil, nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(inner, ",") {
+		items = append(items, unquoteYAML(strings.TrimSpace(item)))
+	}
+	return items, nil
+}
+
+// stripYAMLComment removes a trailing comment, ignoring # inside quotes and URL fragments
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquoteYAML removes the quotes around a single or double quoted scalar
+func unquoteYAML(value string) string {
+	if len(value) >= 2 {
+		if value[0] == '"' && value[len(value)-1] == '"' {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+		}
+		if value[0] == '\'' && value[len(value)-1] == '\'' {
+			return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+	}
+	return value
+}
@@ -0,0 +1,152 @@
+package walrus_go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadNetwork tests loading network profiles from JSON and YAML files
+func TestLoadNetwork(t *testing.T) {
+	files := map[string]string{
+		"staging.json": `{
+			"name": "staging",
+			"default_epochs": 5,
+			"aggregators": ["https://agg1.example.com/", "https://agg2.example.com"],
+			"publishers": ["https://pub.example.com"]
+		}`,
+		"staging.yaml": `# Staging deployment
+name: "staging"
+default_epochs: 5  # two weeks
+aggregators:
+  - https://agg1.example.com/
+  - 'https://agg2.example.com'
+publishers: [https://pub.example.com]
+`,
+		"staging.profile": `
+name: staging
+default_epochs: 5
+aggregators:
+- https://agg1.example.com
+- https://agg2.example.com
+publishers:
+- https://pub.example.com
+`,
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			network, err := LoadNetwork(path)
+			if err != nil {
+				t.Fatalf("LoadNetwork failed: %v", err)
+			}
+			if network.Name != "staging" || network.DefaultEpochs != 5 {
+				t.Errorf("Unexpected network: %+v", network)
+			}
+			if !equalStrings(network.Aggregators, []string{"https://agg1.example.com", "https://agg2.example.com"}) {
+				t.Errorf("Unexpected aggregators: %v", network.Aggregators)
+			}
+			if !equalStrings(network.Publishers, []string{"https://pub.example.com"}) {
+				t.Errorf("Unexpected publishers: %v", network.Publishers)
+			}
+		})
+	}
+}
+
+// TestLoadNetworkInvalid tests that malformed profiles are rejected
+func TestLoadNetworkInvalid(t *testing.T) {
+	files := map[string]string{
+		"empty.yaml":       "name: empty\n",
+		"scheme.yaml":      "aggregators: [ftp://agg.example.com]\n",
+		"unknown.yaml":     "name: x\nregion: eu\naggregators: [https://agg.example.com]\n",
+		"item.yaml":        "- https://agg.example.com\n",
+		"indent.yaml":      "name: x\n  aggregators: [https://agg.example.com]\n",
+		"epochs.yaml":      "default_epochs: many\naggregators: [https://agg.example.com]\n",
+		"negative.json":    `{"default_epochs": -1, "aggregators": ["https://agg.example.com"]}`,
+		"syntax.json":      `{"aggregators": [}`,
+		"not-a-list.yaml":  "aggregators: https://agg.example.com\n",
+		"missing-host.yml": "publishers: ['https://']\n",
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadNetwork(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := LoadNetwork(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+// TestWithNetwork tests that a network profile sets the endpoints and default epochs
+func TestWithNetwork(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`{"newlyCreated":{"blobObject":{"blobId":"test-blob-id"}}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithNetwork(Network{
+		Name:          "custom",
+		Aggregators:   []string{server.URL},
+		Publishers:    []string{server.URL},
+		DefaultEpochs: 7,
+	}))
+
+	if _, err := client.Store([]byte("data"), nil); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if query != "epochs=7" {
+		t.Errorf("Expected the default epochs in the query, got %q", query)
+	}
+
+	if _, err := client.Store([]byte("data"), &StoreOptions{Epochs: 3}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if query != "epochs=3" {
+		t.Errorf("Expected the epochs from the options in the query, got %q", query)
+	}
+}
+
+// TestBuiltinNetworks tests the built-in network profiles
+func TestBuiltinNetworks(t *testing.T) {
+	for _, name := range []string{"testnet", "Mainnet", "LOCAL"} {
+		network, ok := LookupNetwork(name)
+		if !ok {
+			t.Fatalf("Expected network %s to exist", name)
+		}
+		if len(network.Aggregators) == 0 {
+			t.Errorf("Expected %s to have aggregators", name)
+		}
+	}
+	if _, ok := LookupNetwork("devnet"); ok {
+		t.Error("Expected devnet not to exist")
+	}
+
+	client := NewClient(WithNetwork(Local))
+	if client.AggregatorURL[0] != "http://127.0.0.1:31415" {
+		t.Errorf("Unexpected local aggregator: %v", client.AggregatorURL)
+	}
+
+	// Mainnet has no public publishers
+	client = NewClient(WithNetwork(Mainnet))
+	if _, err := client.Store([]byte("data"), nil); err == nil || !strings.Contains(err.Error(), "no endpoints") {
+		t.Errorf("Expected a missing endpoint error, got: %v", err)
+	}
+}
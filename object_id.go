@@ -0,0 +1,47 @@
+package walrus_go
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// objectIDPath returns the aggregator path of the blob with the given Sui object ID
+func objectIDPath(objectID string) string {
+	return fmt.Sprintf("/v1/blobs/by-object-id/%s", url.PathEscape(objectID))
+}
+
+// ReadByObjectID retrieves a blob by its Sui object ID, BlobObject.ID in store responses,
+// and returns its content with its metadata, including the blob attributes the aggregator
+// returned.
+func (c *Client) ReadByObjectID(objectID string, opts *ReadOptions) ([]byte, *BlobMetadata, error) {
+	return c.ReadByObjectIDWithContext(context.Background(), objectID, opts)
+}
+
+// ReadByObjectIDWithContext is like ReadByObjectID but carries ctx through the request and the retry loop
+func (c *Client) ReadByObjectIDWithContext(ctx context.Context, objectID string, opts *ReadOptions) ([]byte, *BlobMetadata, error) {
+	return c.readContent(ctx, objectIDPath(objectID), opts)
+}
+
+// HeadByObjectID retrieves the metadata of a blob by its Sui object ID without downloading the content
+func (c *Client) HeadByObjectID(objectID string) (*BlobMetadata, error) {
+	return c.HeadByObjectIDWithContext(context.Background(), objectID)
+}
+
+// HeadByObjectIDWithContext is like HeadByObjectID but carries ctx through the request and the retry loop
+func (c *Client) HeadByObjectIDWithContext(ctx context.Context, objectID string) (*BlobMetadata, error) {
+	return c.head(ctx, objectIDPath(objectID))
+}
+
+// ReadToReaderByObjectID retrieves a blob by its Sui object ID and returns a stream of its
+// content with its metadata. It is otherwise like ReadToReader; the caller must close the stream.
+func (c *Client) ReadToReaderByObjectID(objectID string, opts *ReadOptions) (io.ReadCloser, *BlobMetadata, error) {
+	return c.ReadToReaderByObjectIDWithContext(context.Background(), objectID, opts)
+}
+
+// ReadToReaderByObjectIDWithContext is like ReadToReaderByObjectID but carries ctx through the
+// request and the retry loop. Cancelling ctx also aborts reads from the returned stream.
+func (c *Client) ReadToReaderByObjectIDWithContext(ctx context.Context, objectID string, opts *ReadOptions) (io.ReadCloser, *BlobMetadata, error) {
+	return c.readStream(ctx, objectIDPath(objectID), opts)
+}
@@ -0,0 +1,85 @@
+package walrus_go
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/namihq/walrus-go/walrustest"
+)
+
+// TestReadByObjectID tests reading a blob and its attributes by Sui object ID
+func TestReadByObjectID(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithPublisherURLs([]string{server.URL}))
+
+	data := []byte(`{"hello":"walrus"}`)
+	resp, err := client.Store(data, nil)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	objectID := resp.NewlyCreated.BlobObject.ID
+	server.SetAttributes(resp.Blob.BlobID, map[string]string{
+		"content-type":        "application/json",
+		"content-disposition": `attachment; filename="hello.json"`,
+		"x-internal":          "not an attribute header",
+	})
+
+	read, metadata, err := client.ReadByObjectID(objectID, nil)
+	if err != nil || string(read) != string(data) {
+		t.Fatalf("ReadByObjectID returned %q, %v", read, err)
+	}
+	expected := map[string]string{
+		"content-type":        "application/json",
+		"content-disposition": `attachment; filename="hello.json"`,
+	}
+	if len(metadata.Attributes) != len(expected) {
+		t.Errorf("Expected attributes %v, got %v", expected, metadata.Attributes)
+	}
+	for key, value := range expected {
+		if metadata.Attributes[key] != value {
+			t.Errorf("Expected attribute %s=%q, got %q", key, value, metadata.Attributes[key])
+		}
+	}
+
+	metadata, err = client.HeadByObjectID(objectID)
+	if err != nil {
+		t.Fatalf("HeadByObjectID failed: %v", err)
+	}
+	if metadata.ContentLength != int64(len(data)) || metadata.ContentType != "application/json" {
+		t.Errorf("Unexpected metadata: %+v", metadata)
+	}
+
+	reader, metadata, err := client.ReadToReaderByObjectID(objectID, nil)
+	if err != nil {
+		t.Fatalf("ReadToReaderByObjectID failed: %v", err)
+	}
+	read, err = io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(read) != string(data) || metadata.Attributes["content-type"] != "application/json" {
+		t.Errorf("ReadToReaderByObjectID returned %q, %+v, %v", read, metadata, err)
+	}
+
+	if _, _, err := client.ReadByObjectID("0x1234", nil); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Expected ErrBlobNotFound, got %v", err)
+	}
+}
+
+// TestAttributeHeaders tests that WithAttributeHeaders selects the headers reported as attributes
+func TestAttributeHeaders(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+
+	blobID := server.PutBlob([]byte("data"), 1)
+	server.SetAttributes(blobID, map[string]string{"x-app-tag": "thumbnail", "content-language": "en"})
+
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithAttributeHeaders([]string{"X-App-Tag"}))
+	metadata, err := client.Head(blobID)
+	if err != nil {
+		t.Fatalf("Head failed: %v", err)
+	}
+	if len(metadata.Attributes) != 1 || metadata.Attributes["x-app-tag"] != "thumbnail" {
+		t.Errorf("Expected only the x-app-tag attribute, got %v", metadata.Attributes)
+	}
+}
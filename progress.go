@@ -0,0 +1,47 @@
+package walrus_go
+
+import "io"
+
+// ProgressFunc is called as a transfer advances with the number of bytes transferred so far and
+// the total, or -1 if the total is unknown. When a transfer is retried, possibly against another
+// endpoint, transferred starts again from zero, unless ReadToFile resumes a broken download.
+type ProgressFunc func(transferred, total int64)
+
+// progressReader reports the bytes read through it to a ProgressFunc
+type progressReader struct {
+	r           io.Reader
+	progress    ProgressFunc
+	transferred int64
+	total       int64
+}
+
+// newProgressReader wraps r to report progress, or returns r itself if progress is nil
+func newProgressReader(r io.Reader, total int64, progress ProgressFunc) io.Reader {
+	if progress == nil {
+		return r
+	}
+	return &progressReader{r: r, progress: progress, total: total}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.transferred += int64(n)
+		r.progress(r.transferred, r.total)
+	}
+	return n, err
+}
+
+// progressReadCloser is a progressReader that keeps the Close method of the wrapped reader
+type progressReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// withProgress wraps a response body to report progress, or returns body itself if progress is nil
+func withProgress(body io.ReadCloser, total int64, progress ProgressFunc) io.ReadCloser {
+	if progress == nil {
+		return body
+	}
+	return &progressReadCloser{Reader: newProgressReader(body, total, progress), Closer: body}
+}
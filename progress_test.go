@@ -0,0 +1,142 @@
+package walrus_go
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/namihq/walrus-go/walrustest"
+)
+
+// progressRecorder records the calls of a ProgressFunc
+type progressRecorder struct {
+	transferred []int64
+	totals      []int64
+}
+
+func (r *progressRecorder) record(transferred, total int64) {
+	r.transferred = append(r.transferred, transferred)
+	r.totals = append(r.totals, total)
+}
+
+// check verifies that progress increased up to size, reported with the given total
+func (r *progressRecorder) check(t *testing.T, name string, size, total int64) {
+	t.Helper()
+	if len(r.transferred) == 0 {
+		t.Errorf("%s: progress was never reported", name)
+		return
+	}
+	for i := range r.transferred {
+		if i > 0 && r.transferred[i] <= r.transferred[i-1] {
+			t.Errorf("%s: progress went from %d to %d", name, r.transferred[i-1], r.transferred[i])
+		}
+		if r.totals[i] != total {
+			t.Errorf("%s: expected total %d, got %d", name, total, r.totals[i])
+		}
+	}
+	if last := r.transferred[len(r.transferred)-1]; last != size {
+		t.Errorf("%s: expected progress to end at %d, got %d", name, size, last)
+	}
+}
+
+// TestProgress tests that uploads and downloads report their progress
+func TestProgress(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithPublisherURLs([]string{server.URL}))
+
+	data := make([]byte, 256*1024)
+	rand.Read(data)
+	size := int64(len(data))
+
+	filePath := filepath.Join(t.TempDir(), "upload")
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var upload progressRecorder
+	resp, err := client.StoreFile(filePath, &StoreOptions{Progress: upload.record})
+	if err != nil {
+		t.Fatalf("StoreFile failed: %v", err)
+	}
+	upload.check(t, "StoreFile", size, size)
+
+	var unknown progressRecorder
+	if _, err := client.StoreFromReader(io.MultiReader(bytes.NewReader(data)), &StoreOptions{Progress: unknown.record}); err != nil {
+		t.Fatalf("StoreFromReader failed: %v", err)
+	}
+	unknown.check(t, "StoreFromReader", size, size)
+
+	var read progressRecorder
+	if _, err := client.Read(resp.Blob.BlobID, &ReadOptions{Progress: read.record}); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	read.check(t, "Read", size, size)
+
+	var toFile progressRecorder
+	if err := client.ReadToFile(resp.Blob.BlobID, filepath.Join(t.TempDir(), "download"), &ReadOptions{Progress: toFile.record}); err != nil {
+		t.Fatalf("ReadToFile failed: %v", err)
+	}
+	toFile.check(t, "ReadToFile", size, size)
+
+	var stream progressRecorder
+	reader, err := client.ReadToReader(resp.Blob.BlobID, &ReadOptions{Progress: stream.record})
+	if err != nil {
+		t.Fatalf("ReadToReader failed: %v", err)
+	}
+	io.Copy(io.Discard, reader)
+	reader.Close()
+	stream.check(t, "ReadToReader", size, size)
+}
+
+// TestProgressEncrypted tests that progress tracks the plaintext on upload and the ciphertext on download
+func TestProgressEncrypted(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithPublisherURLs([]string{server.URL}))
+
+	data := make([]byte, 100*1024)
+	rand.Read(data)
+	encOpts := &EncryptionOptions{Key: make([]byte, 32)}
+
+	var upload progressRecorder
+	resp, err := client.Store(data, &StoreOptions{Encryption: encOpts, Progress: upload.record})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	upload.check(t, "Store", int64(len(data)), int64(len(data)))
+
+	stored, _ := server.Blob(resp.Blob.BlobID)
+	var download progressRecorder
+	read, err := client.Read(resp.Blob.BlobID, &ReadOptions{Encryption: encOpts, Progress: download.record})
+	if err != nil || !bytes.Equal(read, data) {
+		t.Fatalf("Read failed: %v", err)
+	}
+	download.check(t, "Read", int64(len(stored)), int64(len(stored)))
+}
+
+// TestProgressRestartsOnFailover tests that progress starts again when another aggregator is tried
+func TestProgressRestartsOnFailover(t *testing.T) {
+	data := []byte("content fetched twice")
+	tampering, good, blobID := newTamperingServers(data)
+	defer tampering.Close()
+	defer good.Close()
+
+	client := NewClient(WithAggregatorURLs([]string{tampering.URL, good.URL}), WithRetryConfig(0, time.Millisecond))
+
+	var progress progressRecorder
+	_, err := client.Read(blobID, &ReadOptions{
+		Verify:   &VerifyOptions{Length: int64(len(data))},
+		Progress: progress.record,
+	})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(progress.transferred) < 2 || progress.transferred[len(progress.transferred)-1] != int64(len(data)) {
+		t.Errorf("Unexpected progress: %v", progress.transferred)
+	}
+}
@@ -0,0 +1,218 @@
+package walrus_go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/namihq/walrus-go/encryption"
+)
+
+// QuiltPatch is a file to store in a quilt
+type QuiltPatch struct {
+	// Identifier names the patch within the quilt and must be unique in it
+	Identifier string
+	// Content is read once when the quilt is stored
+	Content io.Reader
+	// Tags are optional key-value pairs stored with the patch
+	Tags map[string]string
+}
+
+// QuiltPatchInfo identifies a patch of a stored quilt
+type QuiltPatchInfo struct {
+	Identifier   string `json:"identifier"`
+	QuiltPatchID string `json:"quiltPatchId"`
+}
+
+// QuiltStoreResponse is the response of storing a quilt. BlobStoreResult describes the blob
+// holding the quilt, whose blob ID is the quilt ID.
+type QuiltStoreResponse struct {
+	BlobStoreResult  StoreResponse    `json:"blobStoreResult"`
+	StoredQuiltBlobs []QuiltPatchInfo `json:"storedQuiltBlobs"`
+}
+
+// QuiltID returns the ID of the stored quilt
+func (r *QuiltStoreResponse) QuiltID() string {
+	return r.BlobStoreResult.Blob.BlobID
+}
+
+// PatchID returns the quilt patch ID of the patch with the given identifier
+func (r *QuiltStoreResponse) PatchID(identifier string) (string, bool) {
+	for _, patch := range r.StoredQuiltBlobs {
+		if patch.Identifier == identifier {
+			return patch.QuiltPatchID, true
+		}
+	}
+	return "", false
+}
+
+// StoreQuilt stores many small files as a single quilt, which costs far less than storing
+// each of them as a blob. Epochs, Deletable, SendObjectTo, Encryption, Progress and Attributes
+// of opts apply; with Encryption every patch is encrypted on its own. The quilt is assembled in
+// a temporary file before it is uploaded.
+func (c *Client) StoreQuilt(patches []QuiltPatch, opts *StoreOptions) (*QuiltStoreResponse, error) {
+	return c.StoreQuiltWithContext(context.Background(), patches, opts)
+}
+
+// StoreQuiltWithContext is like StoreQuilt but carries ctx through the request and the retry loop
+func (c *Client) StoreQuiltWithContext(ctx context.Context, patches []QuiltPatch, opts *StoreOptions) (*QuiltStoreResponse, error) {
+	if err := c.checkAttributes(opts); err != nil {
+		return nil, err
+	}
+
+	var cipher encryption.ContentCipher
+	var progress ProgressFunc
+	if opts != nil {
+		if opts.Encryption != nil {
+			var err error
+			if cipher, err = opts.Encryption.getCipher(); err != nil {
+				return nil, fmt.Errorf("failed to create cipher: %w", err)
+			}
+		}
+		progress = opts.Progress
+	}
+
+	body, contentType, err := quiltForm(patches, cipher)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(body.Name())
+	defer body.Close()
+	info, err := body.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+
+	urlStr := "/v1/quilts"
+	if encoded := c.storeParams(opts).Encode(); encoded != "" {
+		urlStr += "?" + encoded
+	}
+
+	newBody := func() io.ReadCloser {
+		return io.NopCloser(newProgressReader(io.NewSectionReader(body, 0, size), size, progress))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, urlStr, newBody())
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	req.GetBody = func() (io.ReadCloser, error) {
+		return newBody(), nil
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.doWithRetry(ctx, req, c.PublisherURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var quiltResp QuiltStoreResponse
+	if err := json.NewDecoder(resp.Body).Decode(&quiltResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	quiltResp.BlobStoreResult.NormalizeBlobResponse()
+	_, err = c.applyAttributes(ctx, &quiltResp.BlobStoreResult, opts)
+	return &quiltResp, err
+}
+
+// quiltForm encodes patches as the multipart form expected by the publisher into a temporary
+// file positioned at its start, and returns it with the form's content type. The caller must
+// close and remove the file.
+func quiltForm(patches []QuiltPatch, cipher encryption.ContentCipher) (*os.File, string, error) {
+	if len(patches) == 0 {
+		return nil, "", fmt.Errorf("quilt has no patches")
+	}
+
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeQuiltForm(form, patches, cipher))
+	}()
+	file, err := spoolToTempFile(pr)
+	// Stops the writer if spooling failed
+	pr.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	return file, form.FormDataContentType(), nil
+}
+
+// writeQuiltForm writes patches to form: a file field per patch named by its identifier, and a
+// _metadata field with the tags of the patches
+func writeQuiltForm(form *multipart.Writer, patches []QuiltPatch, cipher encryption.ContentCipher) error {
+	type patchMetadata struct {
+		Identifier string            `json:"identifier"`
+		Tags       map[string]string `json:"tags"`
+	}
+	var metadata []patchMetadata
+	seen := make(map[string]bool, len(patches))
+
+	for _, patch := range patches {
+		if patch.Identifier == "" {
+			return fmt.Errorf("quilt patch without identifier")
+		}
+		if seen[patch.Identifier] {
+			return fmt.Errorf("duplicate quilt patch identifier %q", patch.Identifier)
+		}
+		seen[patch.Identifier] = true
+
+		part, err := form.CreateFormFile(patch.Identifier, patch.Identifier)
+		if err != nil {
+			return err
+		}
+		if cipher != nil {
+			err = cipher.EncryptStream(patch.Content, part)
+		} else {
+			_, err = io.Copy(part, patch.Content)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read quilt patch %q: %w", patch.Identifier, err)
+		}
+
+		if len(patch.Tags) > 0 {
+			metadata = append(metadata, patchMetadata{Identifier: patch.Identifier, Tags: patch.Tags})
+		}
+	}
+
+	if len(metadata) > 0 {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return err
+		}
+		if err := form.WriteField("_metadata", string(encoded)); err != nil {
+			return err
+		}
+	}
+	return form.Close()
+}
+
+// ReadQuiltPatch retrieves the patch with the given identifier from a quilt
+func (c *Client) ReadQuiltPatch(quiltID, identifier string, opts *ReadOptions) ([]byte, error) {
+	return c.ReadQuiltPatchWithContext(context.Background(), quiltID, identifier, opts)
+}
+
+// ReadQuiltPatchWithContext is like ReadQuiltPatch but carries ctx through the request and the retry loop
+func (c *Client) ReadQuiltPatchWithContext(ctx context.Context, quiltID, identifier string, opts *ReadOptions) ([]byte, error) {
+	urlStr := fmt.Sprintf("/v1/blobs/by-quilt-id/%s/%s", url.PathEscape(quiltID), url.PathEscape(identifier))
+	data, _, err := c.readContent(ctx, urlStr, opts)
+	return data, err
+}
+
+// ReadQuiltPatchByID retrieves a quilt patch by its quilt patch ID
+func (c *Client) ReadQuiltPatchByID(patchID string, opts *ReadOptions) ([]byte, error) {
+	return c.ReadQuiltPatchByIDWithContext(context.Background(), patchID, opts)
+}
+
+// ReadQuiltPatchByIDWithContext is like ReadQuiltPatchByID but carries ctx through the request and the retry loop
+func (c *Client) ReadQuiltPatchByIDWithContext(ctx context.Context, patchID string, opts *ReadOptions) ([]byte, error) {
+	urlStr := fmt.Sprintf("/v1/blobs/by-quilt-patch-id/%s", url.PathEscape(patchID))
+	data, _, err := c.readContent(ctx, urlStr, opts)
+	return data, err
+}
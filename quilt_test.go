@@ -0,0 +1,142 @@
+package walrus_go
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/namihq/walrus-go/walrustest"
+)
+
+func newQuiltPatches(contents map[string]string) []QuiltPatch {
+	var patches []QuiltPatch
+	for identifier, content := range contents {
+		patches = append(patches, QuiltPatch{Identifier: identifier, Content: strings.NewReader(content)})
+	}
+	return patches
+}
+
+// TestStoreQuilt tests storing a quilt and reading its patches by identifier and by patch ID
+func TestStoreQuilt(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithPublisherURLs([]string{server.URL}))
+
+	contents := map[string]string{
+		"a.json":    `{"a":1}`,
+		"b.json":    `{"b":2}`,
+		"thumb.png": "not really a png",
+	}
+	patches := newQuiltPatches(contents)
+	patches[0].Tags = map[string]string{"kind": "test"}
+
+	resp, err := client.StoreQuilt(patches, &StoreOptions{Epochs: 3})
+	if err != nil {
+		t.Fatalf("StoreQuilt failed: %v", err)
+	}
+	if resp.QuiltID() == "" || resp.BlobStoreResult.NewlyCreated == nil {
+		t.Fatalf("Unexpected quilt response: %+v", resp)
+	}
+	if len(resp.StoredQuiltBlobs) != len(contents) {
+		t.Fatalf("Expected %d patches, got %+v", len(contents), resp.StoredQuiltBlobs)
+	}
+
+	if _, tags, ok := server.QuiltPatch(resp.QuiltID(), patches[0].Identifier); !ok || tags["kind"] != "test" {
+		t.Errorf("Expected the tags to be stored, got %v", tags)
+	}
+
+	for identifier, content := range contents {
+		data, err := client.ReadQuiltPatch(resp.QuiltID(), identifier, nil)
+		if err != nil || string(data) != content {
+			t.Errorf("ReadQuiltPatch(%s) returned %q, %v", identifier, data, err)
+		}
+
+		patchID, ok := resp.PatchID(identifier)
+		if !ok {
+			t.Fatalf("No patch ID for %s", identifier)
+		}
+		data, err = client.ReadQuiltPatchByID(patchID, nil)
+		if err != nil || string(data) != content {
+			t.Errorf("ReadQuiltPatchByID(%s) returned %q, %v", identifier, data, err)
+		}
+	}
+
+	if _, err := client.ReadQuiltPatch(resp.QuiltID(), "missing.json", nil); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Expected ErrBlobNotFound, got %v", err)
+	}
+
+	// Patches can be verified
+	sum := sha256.Sum256([]byte(contents["a.json"]))
+	if _, err := client.ReadQuiltPatch(resp.QuiltID(), "a.json", &ReadOptions{Verify: &VerifyOptions{SHA256: sum[:]}}); err != nil {
+		t.Errorf("Verified ReadQuiltPatch failed: %v", err)
+	}
+}
+
+// TestStoreQuiltEncrypted tests that encrypted patches are decrypted on read
+func TestStoreQuiltEncrypted(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithPublisherURLs([]string{server.URL}))
+
+	encOpts := &EncryptionOptions{Key: make([]byte, 32)}
+	resp, err := client.StoreQuilt(newQuiltPatches(map[string]string{"secret.txt": "secret content"}), &StoreOptions{Encryption: encOpts})
+	if err != nil {
+		t.Fatalf("StoreQuilt failed: %v", err)
+	}
+
+	if stored, _, _ := server.QuiltPatch(resp.QuiltID(), "secret.txt"); strings.Contains(string(stored), "secret content") {
+		t.Error("Expected the patch to be stored encrypted")
+	}
+
+	data, err := client.ReadQuiltPatch(resp.QuiltID(), "secret.txt", &ReadOptions{Encryption: encOpts})
+	if err != nil || string(data) != "secret content" {
+		t.Errorf("ReadQuiltPatch returned %q, %v", data, err)
+	}
+}
+
+// TestStoreQuiltInvalid tests that invalid quilts are rejected before uploading
+func TestStoreQuiltInvalid(t *testing.T) {
+	client := NewClient(WithPublisherURLs([]string{"http://127.0.0.1:1"}))
+
+	tests := map[string][]QuiltPatch{
+		"empty":         nil,
+		"no identifier": {{Content: strings.NewReader("x")}},
+		"duplicate":     {{Identifier: "a", Content: strings.NewReader("x")}, {Identifier: "a", Content: strings.NewReader("y")}},
+	}
+	for name, patches := range tests {
+		if _, err := client.StoreQuilt(patches, nil); err == nil || errors.As(err, new(*MultiError)) {
+			t.Errorf("%s: expected a validation error, got %v", name, err)
+		}
+	}
+}
+
+// TestStoreQuiltLarge tests that quilts are not limited by MaxUnknownLengthUploadSize
+func TestStoreQuiltLarge(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(
+		WithAggregatorURLs([]string{server.URL}),
+		WithPublisherURLs([]string{server.URL}),
+		WithMaxUnknownLengthUploadSize(1024),
+	)
+
+	var patches []QuiltPatch
+	for i := 0; i < 600; i++ {
+		content := strings.Repeat(fmt.Sprint(i%10), 10*1024)
+		patches = append(patches, QuiltPatch{Identifier: fmt.Sprintf("thumb-%d.jpg", i), Content: strings.NewReader(content)})
+	}
+	resp, err := client.StoreQuilt(patches, nil)
+	if err != nil {
+		t.Fatalf("StoreQuilt failed: %v", err)
+	}
+	if len(resp.StoredQuiltBlobs) != len(patches) {
+		t.Errorf("Expected %d patches, got %d", len(patches), len(resp.StoredQuiltBlobs))
+	}
+
+	data, err := client.ReadQuiltPatch(resp.QuiltID(), "thumb-599.jpg", nil)
+	if err != nil || string(data) != strings.Repeat("9", 10*1024) {
+		t.Errorf("ReadQuiltPatch returned %d bytes, %v", len(data), err)
+	}
+}
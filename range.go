@@ -0,0 +1,189 @@
+package walrus_go
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ReadRange retrieves length bytes of a blob starting at offset. A negative length reads to the
+// end of the blob. Fewer bytes than requested are returned if the blob ends first.
+//
+// The range is requested with an HTTP Range header; if the aggregator ignores it and returns
+// the whole blob, the range is sliced out on the client. For encrypted blobs, offset and length
+// refer to the plaintext and the read goes through a BlobReader (see OpenBlob).
+func (c *Client) ReadRange(blobID string, offset, length int64, opts *ReadOptions) ([]byte, error) {
+	return c.ReadRangeWithContext(context.Background(), blobID, offset, length, opts)
+}
+
+// ReadRangeWithContext is like ReadRange but carries ctx through the request and the retry loop
+func (c *Client) ReadRangeWithContext(ctx context.Context, blobID string, offset, length int64, opts *ReadOptions) ([]byte, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid range offset: %d", offset)
+	}
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	// Encrypted ranges are offsets into the plaintext, which only a BlobReader can map to chunks
+	if opts != nil && opts.Encryption != nil {
+		blob, err := c.OpenBlobWithContext(ctx, blobID, opts)
+		if err != nil {
+			return nil, err
+		}
+		if offset >= blob.Size() {
+			return []byte{}, nil
+		}
+		if remaining := blob.Size() - offset; length < 0 || length > remaining {
+			length = remaining
+		}
+
+		data := make([]byte, length)
+		n, err := blob.ReadAt(data, offset)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return data[:n], nil
+	}
+
+	body, err := c.readRange(ctx, blobID, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// readRange requests a byte range of a blob and returns a stream of exactly that range
+func (c *Client) readRange(ctx context.Context, blobID string, offset, length int64) (io.ReadCloser, error) {
+	resp, _, err := c.readRangeFrom(ctx, blobID, offset, length, c.AggregatorURL)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// readRangeFrom is like readRange but requests the range from the given aggregators and returns
+// the response, whose body streams exactly the range, and the base URL of the aggregator that
+// served it
+func (c *Client) readRangeFrom(ctx context.Context, blobID string, offset, length int64, urls []string) (*http.Response, string, error) {
+	urlStr := fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Range", formatRange(offset, length))
+
+	resp, baseURL, err := c.doWithRetryFrom(ctx, req, urls)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The aggregator ignored the Range header and sent the whole blob
+	if resp.StatusCode != http.StatusPartialContent {
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil && err != io.EOF {
+			resp.Body.Close()
+			return nil, "", fmt.Errorf("failed to skip to range offset: %w", err)
+		}
+	}
+	if length >= 0 {
+		resp.Body = readCloser{io.LimitReader(resp.Body, length), resp.Body}
+	}
+	return resp, baseURL, nil
+}
+
+// readCloser combines a reader with the closer of the stream it reads from
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// formatRange formats a Range header value for length bytes from offset, or to the end of
+// the blob if length is negative
+func formatRange(offset, length int64) string {
+	if length < 0 {
+		return fmt.Sprintf("bytes=%d-", offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+}
+
+// parseRange parses a single range "bytes=start-end" or "bytes=start-"; end is -1 if open
+func parseRange(value string) (start, end int64, err error) {
+	if !strings.HasPrefix(value, "bytes=") || strings.Contains(value, ",") {
+		return 0, 0, fmt.Errorf("unsupported range: %q", value)
+	}
+
+	first, last, ok := strings.Cut(strings.TrimPrefix(value, "bytes="), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range: %q", value)
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid range: %q", value)
+	}
+	if last == "" {
+		return start, -1, nil
+	}
+	if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid range: %q", value)
+	}
+	return start, end, nil
+}
+
+// parseContentRange parses a Content-Range value "bytes start-end/total"; total is -1 if unknown
+func parseContentRange(value string) (start, end, total int64, err error) {
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, 0, fmt.Errorf("invalid Content-Range: %q", value)
+	}
+
+	rangePart, totalPart, ok := strings.Cut(strings.TrimPrefix(value, "bytes "), "/")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("invalid Content-Range: %q", value)
+	}
+	first, last, ok := strings.Cut(rangePart, "-")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("invalid Content-Range: %q", value)
+	}
+
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid Content-Range: %q", value)
+	}
+	if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+		return 0, 0, 0, fmt.Errorf("invalid Content-Range: %q", value)
+	}
+
+	total = -1
+	if totalPart != "*" {
+		if total, err = strconv.ParseInt(totalPart, 10, 64); err != nil || total <= end {
+			return 0, 0, 0, fmt.Errorf("invalid Content-Range: %q", value)
+		}
+	}
+	return start, end, total, nil
+}
+
+// checkPartialContent validates that a 206 response answers the Range header of req
+func checkPartialContent(req *http.Request, resp *http.Response) error {
+	requested := req.Header.Get("Range")
+	if requested == "" {
+		return fmt.Errorf("unexpected partial content response to a request without a Range header")
+	}
+
+	wantStart, wantEnd, err := parseRange(requested)
+	if err != nil {
+		return err
+	}
+	start, end, _, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return err
+	}
+
+	if start != wantStart || (wantEnd >= 0 && end > wantEnd) {
+		return fmt.Errorf("partial content range %d-%d does not match requested range %s", start, end, requested)
+	}
+	return nil
+}
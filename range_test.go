@@ -0,0 +1,158 @@
+package walrus_go
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRangeTestServer serves content, honoring Range headers only if honorRange is set
+func newRangeTestServer(t *testing.T, content []byte, honorRange bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if honorRange {
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+			return
+		}
+		w.Write(content)
+	}))
+}
+
+// TestReadRange tests reading byte ranges from aggregators with and without Range support
+func TestReadRange(t *testing.T) {
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+
+	tests := []struct {
+		name     string
+		offset   int64
+		length   int64
+		expected string
+	}{
+		{name: "middle", offset: 10, length: 6, expected: "abcdef"},
+		{name: "start", offset: 0, length: 3, expected: "012"},
+		{name: "to end", offset: 30, length: -1, expected: "uvwxyz"},
+		{name: "past end", offset: 33, length: 10, expected: "xyz"},
+		{name: "empty", offset: 5, length: 0, expected: ""},
+	}
+
+	for _, honorRange := range []bool{true, false} {
+		server := newRangeTestServer(t, content, honorRange)
+		defer server.Close()
+		client := NewClient(
+			WithRetryConfig(0, 0),
+			WithAggregatorURLs([]string{server.URL}),
+		)
+
+		for _, tt := range tests {
+			name := tt.name + " with range support"
+			if !honorRange {
+				name = tt.name + " without range support"
+			}
+			t.Run(name, func(t *testing.T) {
+				data, err := client.ReadRange("test-blob", tt.offset, tt.length, nil)
+				if err != nil {
+					t.Fatalf("Failed to read range: %v", err)
+				}
+				if string(data) != tt.expected {
+					t.Errorf("Expected %q, got %q", tt.expected, string(data))
+				}
+			})
+		}
+	}
+}
+
+// TestReadRangeValidatesPartialContent tests that mismatched 206 responses are rejected and retried
+func TestReadRangeValidatesPartialContent(t *testing.T) {
+	attemptCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attemptCount++
+		if r.Header.Get("Range") != "bytes=4-7" {
+			t.Errorf("Expected Range header bytes=4-7, got %q", r.Header.Get("Range"))
+		}
+		if attemptCount == 1 {
+			// Wrong range on the first attempt
+			w.Header().Set("Content-Range", "bytes 0-3/10")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("0123"))
+			return
+		}
+		w.Header().Set("Content-Range", "bytes 4-7/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("4567"))
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithRetryConfig(1, 10*time.Millisecond),
+		WithAggregatorURLs([]string{server.URL}),
+	)
+
+	data, err := client.ReadRange("test-blob", 4, 4, nil)
+	if err != nil {
+		t.Fatalf("Expected successful read after retry, got error: %v", err)
+	}
+	if string(data) != "4567" {
+		t.Errorf("Expected %q, got %q", "4567", string(data))
+	}
+	if attemptCount != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attemptCount)
+	}
+}
+
+// TestUnexpectedPartialContent tests that a 206 response to a request without a Range header is rejected
+func TestUnexpectedPartialContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-3/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("0123"))
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithRetryConfig(0, 0),
+		WithAggregatorURLs([]string{server.URL}),
+	)
+
+	_, err := client.Read("test-blob", nil)
+	if err == nil || !strings.Contains(err.Error(), "partial content") {
+		t.Errorf("Expected partial content error, got: %v", err)
+	}
+}
+
+// TestParseContentRange tests parsing of Content-Range header values
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value     string
+		start     int64
+		end       int64
+		total     int64
+		expectErr bool
+	}{
+		{value: "bytes 0-9/100", start: 0, end: 9, total: 100},
+		{value: "bytes 10-19/*", start: 10, end: 19, total: -1},
+		{value: "bytes 10-9/100", expectErr: true},
+		{value: "bytes 0-100/100", expectErr: true},
+		{value: "items 0-9/100", expectErr: true},
+		{value: "", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		start, end, total, err := parseContentRange(tt.value)
+		if tt.expectErr {
+			if err == nil {
+				t.Errorf("Expected error for %q, got none", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.value, err)
+			continue
+		}
+		if start != tt.start || end != tt.end || total != tt.total {
+			t.Errorf("parseContentRange(%q) = %d, %d, %d; expected %d, %d, %d",
+				tt.value, start, end, total, tt.start, tt.end, tt.total)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package walrus_go
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides whether a failed attempt is retried and how long to wait first
+type RetryPolicy interface {
+	// NextDelay is called after the given attempt (1-based) failed with err, elapsed after the
+	// first attempt started. It returns the delay before the next attempt, or false to stop.
+	NextDelay(attempt int, elapsed time.Duration, err error) (time.Duration, bool)
+}
+
+// RetryEvent describes a retry that is about to happen, as passed to the hook set with WithRetryHook
+type RetryEvent struct {
+	// Attempt is the 1-based number of the attempt that failed
+	Attempt int
+	// Err is the error of the failed attempt, usually an *APIError
+	Err error
+	// Delay is how long the client waits before the next attempt
+	Delay time.Duration
+}
+
+// DefaultRetryPolicy retries retryable errors (see IsRetryable) with exponential backoff and jitter.
+// A Retry-After header on the failed response takes precedence over a shorter backoff, up to MaxDelay.
+type DefaultRetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the first attempt
+	MaxRetries int
+	// InitialDelay is the delay before the first retry
+	InitialDelay time.Duration
+	// MaxDelay caps the backoff delay and Retry-After; zero means no cap
+	MaxDelay time.Duration
+	// Multiplier grows the delay after each retry; values below 1 are treated as 1
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction of it, e.g. 0.2 for ±20%
+	Jitter float64
+	// MaxElapsedTime stops retrying once the next attempt would start after this much time
+	// since the first one; zero means no limit
+	MaxElapsedTime time.Duration
+}
+
+// NewDefaultRetryPolicy creates a policy with the given retry count and initial delay,
+// doubling the delay up to 30 seconds with 20% jitter
+func NewDefaultRetryPolicy(maxRetries int, initialDelay time.Duration) *DefaultRetryPolicy {
+	return &DefaultRetryPolicy{
+		MaxRetries:   maxRetries,
+		InitialDelay: initialDelay,
+		MaxDelay:     30 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+	}
+}
+
+// NextDelay implements RetryPolicy
+func (p *DefaultRetryPolicy) NextDelay(attempt int, elapsed time.Duration, err error) (time.Duration, bool) {
+	if attempt > p.MaxRetries || !IsRetryable(err) {
+		return 0, false
+	}
+
+	multiplier := math.Max(p.Multiplier, 1)
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	next := time.Duration(delay)
+
+	// Retry-After is capped too, so that one endpoint asking for a long pause can't stall the
+	// client; the next attempt usually goes to another endpoint anyway
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > next {
+		next = apiErr.RetryAfter
+		if p.MaxDelay > 0 && next > p.MaxDelay {
+			next = p.MaxDelay
+		}
+	}
+
+	if p.MaxElapsedTime > 0 && elapsed+next > p.MaxElapsedTime {
+		return 0, false
+	}
+	return next, true
+}
+
+// IsRetryable reports whether a failed attempt is worth retrying. Transport errors, invalid
+// responses, 5xx responses, 408 Request Timeout and 429 Too Many Requests are retryable;
+// other client errors such as 400 or 404 are not, since another attempt would fail the same way.
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch {
+	case apiErr.StatusCode == 0 || apiErr.Err != nil:
+		return true
+	case apiErr.StatusCode == http.StatusRequestTimeout, apiErr.StatusCode == http.StatusTooManyRequests:
+		return true
+	case apiErr.StatusCode >= 500:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
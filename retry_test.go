@@ -0,0 +1,171 @@
+package walrus_go
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRetryClassification tests which responses are retried by the default policy
+func TestRetryClassification(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int32
+	}{
+		{name: "bad request", status: http.StatusBadRequest, attempts: 1},
+		{name: "not found", status: http.StatusNotFound, attempts: 1},
+		{name: "request timeout", status: http.StatusRequestTimeout, attempts: 3},
+		{name: "too many requests", status: http.StatusTooManyRequests, attempts: 3},
+		{name: "server error", status: http.StatusInternalServerError, attempts: 3},
+		{name: "bad gateway", status: http.StatusBadGateway, attempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := NewClient(
+				WithRetryConfig(2, time.Millisecond),
+				WithAggregatorURLs([]string{server.URL}),
+			)
+
+			if _, err := client.Read("test-blob", nil); err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.attempts {
+				t.Errorf("Expected %d attempts, got %d", tt.attempts, got)
+			}
+		})
+	}
+}
+
+// TestRetryAfter tests that a Retry-After header delays the next attempt
+func TestRetryAfter(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var events []RetryEvent
+	client := NewClient(
+		WithRetryConfig(1, time.Millisecond),
+		WithAggregatorURLs([]string{server.URL}),
+		WithRetryHook(func(e RetryEvent) {
+			events = append(events, e)
+		}),
+	)
+
+	start := time.Now()
+	if _, err := client.Read("test-blob", nil); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected the retry to wait for Retry-After, took %v", elapsed)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 retry event, got %d", len(events))
+	}
+	if events[0].Attempt != 1 || events[0].Delay != time.Second {
+		t.Errorf("Unexpected retry event: %+v", events[0])
+	}
+	var apiErr *APIError
+	if !errors.As(events[0].Err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected a 429 APIError in the retry event, got: %v", events[0].Err)
+	}
+}
+
+// TestDefaultRetryPolicyBackoff tests the exponential backoff, cap and jitter of the default policy
+func TestDefaultRetryPolicyBackoff(t *testing.T) {
+	policy := NewDefaultRetryPolicy(10, 100*time.Millisecond)
+	policy.MaxDelay = time.Second
+	policy.Jitter = 0
+
+	serverErr := &APIError{StatusCode: http.StatusServiceUnavailable}
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+	}
+	for i, want := range expected {
+		delay, ok := policy.NextDelay(i+1, 0, serverErr)
+		if !ok || delay != want {
+			t.Errorf("Attempt %d: expected %v, got %v (retry %v)", i+1, want, delay, ok)
+		}
+	}
+
+	if _, ok := policy.NextDelay(11, 0, serverErr); ok {
+		t.Error("Expected no retry after MaxRetries")
+	}
+
+	policy.MaxElapsedTime = time.Second
+	if _, ok := policy.NextDelay(1, 950*time.Millisecond, serverErr); ok {
+		t.Error("Expected no retry beyond MaxElapsedTime")
+	}
+
+	policy.MaxElapsedTime = 0
+
+	// Retry-After beyond MaxDelay is capped
+	if delay, ok := policy.NextDelay(1, 0, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 24 * time.Hour}); !ok || delay != time.Second {
+		t.Errorf("Expected Retry-After to be capped at MaxDelay, got %v (retry %v)", delay, ok)
+	}
+	if delay, _ := policy.NextDelay(1, 0, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 500 * time.Millisecond}); delay != 500*time.Millisecond {
+		t.Errorf("Expected Retry-After below MaxDelay to be kept, got %v", delay)
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay, _ := policy.NextDelay(1, 0, serverErr)
+		if delay < 50*time.Millisecond || delay > 150*time.Millisecond {
+			t.Fatalf("Jittered delay %v out of range", delay)
+		}
+	}
+}
+
+// TestCustomRetryPolicy tests that a custom policy replaces the default one
+func TestCustomRetryPolicy(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithAggregatorURLs([]string{server.URL}),
+		WithRetryPolicy(retryPolicyFunc(func(attempt int, elapsed time.Duration, err error) (time.Duration, bool) {
+			return 0, attempt < 4
+		})),
+	)
+
+	_, err := client.Read("test-blob", nil)
+	if !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("Expected ErrBlobNotFound, got: %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 4 {
+		t.Errorf("Expected 4 attempts, got %d", got)
+	}
+}
+
+type retryPolicyFunc func(attempt int, elapsed time.Duration, err error) (time.Duration, bool)
+
+func (f retryPolicyFunc) NextDelay(attempt int, elapsed time.Duration, err error) (time.Duration, bool) {
+	return f(attempt, elapsed, err)
+}
@@ -0,0 +1,272 @@
+package walrus_go
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// ErrContentMismatch means the content returned by an aggregator failed verification
+var ErrContentMismatch = errors.New("content verification failed")
+
+// VerifyOptions configures integrity checks of the content returned by aggregators. The checks
+// apply to the blob as stored, which for encrypted blobs is the ciphertext.
+type VerifyOptions struct {
+	// SHA256 is the expected SHA-256 digest of the content, if not empty
+	SHA256 []byte
+	// Length is the expected length of the content, e.g. BlobMetadata.ContentLength, if positive
+	Length int64
+}
+
+// contentVerifier checks content written to it against VerifyOptions
+type contentVerifier struct {
+	opts *VerifyOptions
+	hash hash.Hash
+	n    int64
+}
+
+func newContentVerifier(opts *VerifyOptions) *contentVerifier {
+	v := &contentVerifier{opts: opts}
+	if len(opts.SHA256) > 0 {
+		v.hash = sha256.New()
+	}
+	return v
+}
+
+func (v *contentVerifier) Write(p []byte) (int, error) {
+	if v.hash != nil {
+		v.hash.Write(p)
+	}
+	v.n += int64(len(p))
+	return len(p), nil
+}
+
+// check verifies the length and digest of everything written so far
+func (v *contentVerifier) check() error {
+	if v.opts.Length > 0 && v.n != v.opts.Length {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrContentMismatch, v.opts.Length, v.n)
+	}
+	if v.hash != nil {
+		if sum := v.hash.Sum(nil); !bytes.Equal(sum, v.opts.SHA256) {
+			return fmt.Errorf("%w: expected SHA-256 %x, got %x", ErrContentMismatch, v.opts.SHA256, sum)
+		}
+	}
+	return nil
+}
+
+// downloadSink receives a verified download and can be reset for the next attempt
+type downloadSink interface {
+	io.Writer
+	// reset discards the content of a failed attempt
+	reset() error
+}
+
+// bufferSink keeps a download in memory
+type bufferSink struct {
+	bytes.Buffer
+}
+
+func (s *bufferSink) reset() error {
+	s.Buffer.Reset()
+	return nil
+}
+
+// fileSink writes a download to a file
+type fileSink struct {
+	*os.File
+}
+
+func (s *fileSink) reset() error {
+	if err := s.Truncate(0); err != nil {
+		return err
+	}
+	_, err := s.Seek(0, io.SeekStart)
+	return err
+}
+
+// readVerified downloads the content at urlStr into sink and verifies it. When an aggregator
+// returns content that fails verification, or a body that breaks off, the download is repeated
+// with the remaining aggregators. If all of them fail, the returned error is a *MultiError. On
+// success, it returns the metadata of the response that was verified.
+func (c *Client) readVerified(ctx context.Context, urlStr string, opts *VerifyOptions, sink downloadSink, progress ProgressFunc) (*BlobMetadata, error) {
+	remaining := append([]string(nil), c.AggregatorURL...)
+	var failures []error
+
+	// Every round drops the endpoint that served bad content, so there are at most as many
+	// rounds as aggregators
+	for round := 0; round < len(c.AggregatorURL) && len(remaining) > 0; round++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, baseURL, err := c.doReadFrom(ctx, req, remaining)
+		if err != nil {
+			var multiErr *MultiError
+			if len(failures) > 0 && errors.As(err, &multiErr) {
+				return nil, &MultiError{Errors: append(failures, multiErr.Errors...)}
+			}
+			return nil, err
+		}
+
+		body := newProgressReader(resp.Body, resp.ContentLength, progress)
+		verifyErr, err := c.verifyDownload(opts, body, sink)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if verifyErr == nil {
+			return c.blobMetadata(resp), nil
+		}
+
+		// The endpoint served bad content, so don't ask it again
+		c.endpoints.recordFailure(baseURL, verifyErr)
+		failures = append(failures, &APIError{
+			StatusCode: resp.StatusCode,
+			URL:        resp.Request.URL.String(),
+			Attempt:    len(failures) + 1,
+			Err:        verifyErr,
+		})
+		remaining = removeString(remaining, baseURL)
+
+		if err := sink.reset(); err != nil {
+			return nil, fmt.Errorf("failed to reset download: %w", err)
+		}
+	}
+	return nil, &MultiError{Errors: failures}
+}
+
+// readVerifiedBytes implements readContent with verification, downloading into memory and
+// decrypting only once the content has been verified
+func (c *Client) readVerifiedBytes(ctx context.Context, urlStr string, opts *ReadOptions) ([]byte, *BlobMetadata, error) {
+	var sink bufferSink
+	metadata, err := c.readVerified(ctx, urlStr, opts.Verify, &sink, opts.Progress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if opts.Encryption != nil {
+		cipher, err := opts.Encryption.getCipher()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+
+		var decryptedBuf bytes.Buffer
+		if err := cipher.DecryptStream(&sink.Buffer, &decryptedBuf); err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt data: %w", err)
+		}
+		return decryptedBuf.Bytes(), metadata, nil
+	}
+	return sink.Bytes(), metadata, nil
+}
+
+// readVerifiedToFile implements ReadToFile with verification. The content is downloaded into a
+// temporary file next to filePath, so filePath is only written once the content is verified.
+func (c *Client) readVerifiedToFile(ctx context.Context, blobID, filePath string, opts *ReadOptions) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	urlStr := fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID))
+	if _, err := c.readVerified(ctx, urlStr, opts.Verify, &fileSink{tmpFile}, opts.Progress); err != nil {
+		return err
+	}
+
+	if opts.Encryption != nil {
+		cipher, err := opts.Encryption.getCipher()
+		if err != nil {
+			return fmt.Errorf("failed to create cipher: %w", err)
+		}
+		if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		// Decrypt into a second temporary file, so that a failed decryption leaves filePath as it was
+		outFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(outFile.Name())
+		defer outFile.Close()
+		if err := cipher.DecryptStream(tmpFile, outFile); err != nil {
+			return fmt.Errorf("failed to decrypt data: %w", err)
+		}
+		if err := outFile.Close(); err != nil {
+			return err
+		}
+		return os.Rename(outFile.Name(), filePath)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filePath)
+}
+
+// verifyDownload copies body into sink and verifies it. It returns the verification error,
+// which includes failures reading body, separately from errors writing to sink.
+func (c *Client) verifyDownload(opts *VerifyOptions, body io.Reader, sink downloadSink) (verifyErr error, err error) {
+	verifier := newContentVerifier(opts)
+	src := &readErrorReader{r: body}
+	if _, err := io.Copy(io.MultiWriter(sink, verifier), src); err != nil {
+		if src.err == nil {
+			return nil, fmt.Errorf("failed to write download: %w", err)
+		}
+		return fmt.Errorf("failed to read content: %w", err), nil
+	}
+
+	return verifier.check(), nil
+}
+
+// verifyingReadCloser checks a stream against VerifyOptions when it reaches the end, returning
+// the verification error instead of io.EOF
+type verifyingReadCloser struct {
+	io.ReadCloser
+	verifier *contentVerifier
+}
+
+func (r *verifyingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.verifier.Write(p[:n])
+	if err == io.EOF {
+		if verifyErr := r.verifier.check(); verifyErr != nil {
+			return n, verifyErr
+		}
+	}
+	return n, err
+}
+
+// readErrorReader remembers the error returned by the underlying reader
+type readErrorReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrorReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+func removeString(list []string, value string) []string {
+	result := list[:0]
+	for _, item := range list {
+		if item != value {
+			result = append(result, item)
+		}
+	}
+	return result
+}
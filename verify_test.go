@@ -0,0 +1,195 @@
+package walrus_go
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/namihq/walrus-go/walrustest"
+)
+
+// newTamperingServers returns a fake that serves tampered content for every blob and a good one
+// holding data
+func newTamperingServers(data []byte) (tampering, good *walrustest.Server, blobID string) {
+	tampering = walrustest.NewServer()
+	tampering.InjectFault(walrustest.Fault{Method: http.MethodGet, Path: "/v1/blobs/", StatusCode: http.StatusOK, Body: "tampered"})
+	good = walrustest.NewServer()
+	blobID = good.PutBlob(data, 1)
+	return tampering, good, blobID
+}
+
+// TestVerifiedReadFailover tests that content failing verification is fetched from another aggregator
+func TestVerifiedReadFailover(t *testing.T) {
+	data := []byte("content that must not be tampered with")
+	tampering, good, blobID := newTamperingServers(data)
+	defer tampering.Close()
+	defer good.Close()
+
+	sum := sha256.Sum256(data)
+	verifyOpts := []*VerifyOptions{
+		{SHA256: sum[:]},
+		{Length: int64(len(data))},
+	}
+
+	for _, verify := range verifyOpts {
+		client := NewClient(
+			WithAggregatorURLs([]string{tampering.URL, good.URL}),
+			WithRetryConfig(0, time.Millisecond),
+		)
+
+		read, err := client.Read(blobID, &ReadOptions{Verify: verify})
+		if err != nil || string(read) != string(data) {
+			t.Errorf("Read with %+v returned %q, %v", verify, read, err)
+		}
+
+		// The tampering aggregator counts as failed
+		stats := client.EndpointStats()
+		if stats[0].URL != tampering.URL || stats[0].Failures != 1 {
+			t.Errorf("Expected a failure for the tampering aggregator, got %+v", stats[0])
+		}
+
+		filePath := filepath.Join(t.TempDir(), "blob")
+		if err := client.ReadToFile(blobID, filePath, &ReadOptions{Verify: verify}); err != nil {
+			t.Fatalf("ReadToFile failed: %v", err)
+		}
+		if written, _ := os.ReadFile(filePath); string(written) != string(data) {
+			t.Errorf("Expected file content %q, got %q", data, written)
+		}
+		if entries, _ := os.ReadDir(filepath.Dir(filePath)); len(entries) != 1 {
+			t.Errorf("Expected the temporary file to be removed, got %d entries", len(entries))
+		}
+	}
+}
+
+// TestVerifiedReadMismatch tests that a read fails when no aggregator returns the expected content
+func TestVerifiedReadMismatch(t *testing.T) {
+	data := []byte("original content")
+	tampering, good, blobID := newTamperingServers(data)
+	defer tampering.Close()
+	defer good.Close()
+
+	client := NewClient(WithAggregatorURLs([]string{tampering.URL}), WithRetryConfig(0, time.Millisecond))
+	sum := sha256.Sum256(data)
+
+	_, err := client.Read(blobID, &ReadOptions{Verify: &VerifyOptions{SHA256: sum[:]}})
+	if !errors.Is(err, ErrContentMismatch) {
+		t.Errorf("Expected ErrContentMismatch, got %v", err)
+	}
+
+	// The existing file is left untouched
+	filePath := filepath.Join(t.TempDir(), "blob")
+	if err := os.WriteFile(filePath, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = client.ReadToFile(blobID, filePath, &ReadOptions{Verify: &VerifyOptions{SHA256: sum[:]}})
+	if !errors.Is(err, ErrContentMismatch) {
+		t.Errorf("Expected ErrContentMismatch, got %v", err)
+	}
+	if written, _ := os.ReadFile(filePath); string(written) != "previous" {
+		t.Errorf("Expected the file to be unchanged, got %q", written)
+	}
+}
+
+// TestVerifiedReadToReader tests that streams report a mismatch at the end instead of io.EOF
+func TestVerifiedReadToReader(t *testing.T) {
+	data := []byte("streamed content")
+	tampering, good, blobID := newTamperingServers(data)
+	defer tampering.Close()
+	defer good.Close()
+
+	sum := sha256.Sum256(data)
+	verify := &VerifyOptions{SHA256: sum[:], Length: int64(len(data))}
+
+	client := NewClient(WithAggregatorURLs([]string{good.URL}))
+	reader, err := client.ReadToReader(blobID, &ReadOptions{Verify: verify})
+	if err != nil {
+		t.Fatalf("ReadToReader failed: %v", err)
+	}
+	read, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(read) != string(data) {
+		t.Errorf("ReadAll returned %q, %v", read, err)
+	}
+
+	client = NewClient(WithAggregatorURLs([]string{tampering.URL}))
+	reader, err = client.ReadToReader(blobID, &ReadOptions{Verify: verify})
+	if err != nil {
+		t.Fatalf("ReadToReader failed: %v", err)
+	}
+	_, err = io.ReadAll(reader)
+	reader.Close()
+	if !errors.Is(err, ErrContentMismatch) {
+		t.Errorf("Expected ErrContentMismatch, got %v", err)
+	}
+}
+
+// TestVerifiedReadRedirect tests that an aggregator redirecting to tampered content is dropped
+// like one serving it directly
+func TestVerifiedReadRedirect(t *testing.T) {
+	data := []byte("content behind a redirect")
+	tampering, good, blobID := newTamperingServers(data)
+	defer tampering.Close()
+	defer good.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, tampering.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	sum := sha256.Sum256(data)
+	for _, hedged := range []bool{false, true} {
+		options := []ClientOption{WithRetryConfig(0, time.Millisecond)}
+		if hedged {
+			options = append(options, WithHedgedReads(time.Second, 1))
+		}
+
+		client := NewClient(append(options, WithAggregatorURLs([]string{redirect.URL}))...)
+		_, err := client.Read(blobID, &ReadOptions{Verify: &VerifyOptions{SHA256: sum[:]}})
+		if !errors.Is(err, ErrContentMismatch) {
+			t.Errorf("hedged=%v: expected ErrContentMismatch, got %v", hedged, err)
+		}
+
+		client = NewClient(append(options, WithAggregatorURLs([]string{redirect.URL, good.URL}))...)
+		read, err := client.Read(blobID, &ReadOptions{Verify: &VerifyOptions{SHA256: sum[:]}})
+		if err != nil || string(read) != string(data) {
+			t.Errorf("hedged=%v: Read returned %q, %v", hedged, read, err)
+		}
+	}
+}
+
+// TestVerifiedReadToFileDecryptFailure tests that a failed decryption leaves the target file as it was
+func TestVerifiedReadToFileDecryptFailure(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := NewClient(WithAggregatorURLs([]string{server.URL}), WithPublisherURLs([]string{server.URL}))
+
+	resp, err := client.Store([]byte("encrypted content"), &StoreOptions{Encryption: &EncryptionOptions{Key: bytes.Repeat([]byte{1}, 32)}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	ciphertext, _ := server.Blob(resp.Blob.BlobID)
+
+	filePath := filepath.Join(t.TempDir(), "blob")
+	if err := os.WriteFile(filePath, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = client.ReadToFile(resp.Blob.BlobID, filePath, &ReadOptions{
+		Encryption: &EncryptionOptions{Key: bytes.Repeat([]byte{2}, 32)},
+		Verify:     &VerifyOptions{Length: int64(len(ciphertext))},
+	})
+	if err == nil {
+		t.Fatal("Expected a decryption error")
+	}
+	if written, _ := os.ReadFile(filePath); string(written) != "previous" {
+		t.Errorf("Expected the file to be unchanged, got %q", written)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(filePath)); len(entries) != 1 {
+		t.Errorf("Expected the temporary files to be removed, got %d entries", len(entries))
+	}
+}
@@ -2,13 +2,16 @@ package walrus_go
 
 import (
     "bytes"
+    "context"
     "encoding/json"
+    "errors"
     "fmt"
     "io"
     "net/http"
     "net/url"
     "os"
     "strconv"
+    "sync"
     "time"
 
     "github.com/namihq/walrus-go/encryption"
@@ -26,15 +29,35 @@ type Client struct {
     PublisherURL  []string
     httpClient    *http.Client
     retryConfig   RetryConfig // Add retry configuration
+    retryPolicy   RetryPolicy
+    retryHook     func(RetryEvent)
+    hedgeDelay    time.Duration
+    hedgeExtra    int
+    defaultEpochs int
+    blobIDs       BlobIDComputer
+    auth          AuthProvider
+    authURLs      []string
+    headers       http.Header
+    headerURLs    []string
+    attrHeaders   []string
+    attrSetter    AttributeSetter
+    endpoints     *endpointPool
+    healthMu      sync.Mutex
+    health        *healthChecker
     // MaxUnknownLengthUploadSize specifies the maximum allowed size in bytes for uploads
     // when the content length is not known in advance (i.e., contentLength <= 0).
     // In such cases, the entire content must be read into memory to determine its size,
     // which could potentially cause memory issues with very large uploads.
     // This limit helps prevent memory exhaustion in those scenarios.
+    // Readers implementing io.Seeker and uploads via StoreFromReaderWithLength are streamed
+    // and not subject to this limit. Exceeding it returns ErrUploadTooLarge.
     // Default is 5MB.
     MaxUnknownLengthUploadSize int64
 }
 
+// maxErrorBodySize limits how much of an error response body is kept in an APIError
+const maxErrorBodySize = 64 * 1024
+
 // ClientOption defines a function type that modifies Client options
 type ClientOption func(*Client)
 
@@ -65,13 +88,31 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
     }
 }
 
-// WithRetryConfig sets the retry configuration for the client
+// WithRetryConfig sets the retry configuration for the client.
+// Retries use a DefaultRetryPolicy with retryDelay as the initial backoff delay.
 func WithRetryConfig(maxRetries int, retryDelay time.Duration) ClientOption {
     return func(c *Client) {
         c.retryConfig = RetryConfig{
             MaxRetries: maxRetries,
             RetryDelay: retryDelay,
         }
+        c.retryPolicy = nil
+    }
+}
+
+// WithRetryPolicy sets a custom retry policy, replacing the one derived from the retry configuration
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+    return func(c *Client) {
+        if policy != nil {
+            c.retryPolicy = policy
+        }
+    }
+}
+
+// WithRetryHook sets a function that is called before every retry, e.g. for logging
+func WithRetryHook(hook func(RetryEvent)) ClientOption {
+    return func(c *Client) {
+        c.retryHook = hook
     }
 }
 
@@ -92,14 +133,16 @@ func WithMaxUnknownLengthUploadSize(maxSize int64) ClientOption {
 func NewClient(opts ...ClientOption) *Client {
     // Create client with default values
     client := &Client{
-        AggregatorURL: DefaultTestnetAggregators,
-        PublisherURL:  DefaultTestnetPublishers,
+        AggregatorURL: Testnet.Aggregators,
+        PublisherURL:  Testnet.Publishers,
+        defaultEpochs: Testnet.DefaultEpochs,
         httpClient:    &http.Client{},
         retryConfig: RetryConfig{
             MaxRetries: 5,                      // Default to 5 retries
             RetryDelay: 500 * time.Millisecond, // Default to 500ms delay
         },
         MaxUnknownLengthUploadSize: 5 * 1024 * 1024, // Default to 5MB
+        endpoints:                  newEndpointPool(),
     }
 
     // Apply all options
@@ -114,11 +157,18 @@ func NewClient(opts ...ClientOption) *Client {
 type EncryptionOptions struct {
     // Key used for encryption/decryption
     Key []byte
+    // KeyProvider enables envelope encryption: each blob is encrypted with a random data key
+    // that is wrapped by the provider and stored in the blob header, so reads unwrap it
+    // automatically and keys can be rotated. When set, Key and IV are ignored.
+    KeyProvider encryption.KeyProvider
     // Suite specifies the cipher suite to use
     // Default is AES256GCM if not specified
     Suite encryption.CipherSuite
     // IV is only required for certain cipher suites (e.g., AES256CBC)
     IV []byte
+    // AllowLegacyGCM lets reads decrypt AES256GCM blobs written by releases before the
+    // versioned stream format. That format is insecure, so only enable it to migrate old blobs.
+    AllowLegacyGCM bool
 }
 
 // StoreOptions defines options for storing data
@@ -130,12 +180,33 @@ type StoreOptions struct {
     SendObjectTo string
     // Encryption configuration, if nil encryption is disabled
     Encryption *EncryptionOptions
+    // Progress, if set, is called as the content is read for upload, which for encrypted
+    // uploads also tracks encryption. The total is the content length, or -1 if unknown.
+    Progress ProgressFunc
+    // Attributes are set on the blob object after the upload. Requires WithAttributeSetter. The
+    // store fails if the blob was already certified, since no blob object is created then, but
+    // the response is returned along with the error.
+    Attributes Attributes
 }
 
 // ReadOptions defines options for reading data
 type ReadOptions struct {
     // Encryption configuration for decryption, if nil decryption is disabled
     Encryption *EncryptionOptions
+    // Verify configures integrity checks of the content, if nil the content is not verified
+    Verify *VerifyOptions
+    // Progress, if set, is called as the stored content is received. The total is the
+    // Content-Length of the response, or -1 if unknown. It applies to Read, ReadToFile and
+    // ReadToReader.
+    Progress ProgressFunc
+}
+
+// progress returns the progress callback of opts, which may be nil
+func (opts *ReadOptions) progress() ProgressFunc {
+    if opts == nil {
+        return nil
+    }
+    return opts.Progress
 }
 
 // BlobInfo represents the information returned after storing data
@@ -207,10 +278,20 @@ type BlobMetadata struct {
     ContentType   string `json:"content-type"`
     LastModified  string `json:"last-modified"`
     ETag          string `json:"etag"`
+    // Attributes holds the blob attributes the aggregator returned as response headers, keyed
+    // by lowercase header name. See WithAttributeHeaders.
+    Attributes Attributes `json:"attributes,omitempty"`
 }
 
 // getCipher creates a cipher based on the encryption options
 func (opts *EncryptionOptions) getCipher() (encryption.ContentCipher, error) {
+    if opts != nil && opts.KeyProvider != nil {
+        if opts.Suite != "" && opts.Suite != encryption.AES256GCM {
+            return nil, fmt.Errorf("envelope encryption only supports %s", encryption.AES256GCM)
+        }
+        return encryption.NewEnvelopeCipher(opts.KeyProvider)
+    }
+
     if opts == nil || len(opts.Key) == 0 {
         return nil, fmt.Errorf("encryption key is required")
     }
@@ -224,57 +305,97 @@ func (opts *EncryptionOptions) getCipher() (encryption.ContentCipher, error) {
         return nil, fmt.Errorf("unsupported cipher suite: %s", opts.Suite)
     }
 
+    if opts.Suite == encryption.AES256GCM && opts.AllowLegacyGCM {
+        return encryption.NewGCMContentCipher(opts.Key, encryption.WithLegacyGCMDecryption())
+    }
+
     return encryption.NewCipher(opts.Suite, opts.Key, opts.IV)
 }
 
 // Store stores data on the Walrus Publisher and returns the complete store response
 func (c *Client) Store(data []byte, opts *StoreOptions) (*StoreResponse, error) {
-    urlStr := "/v1/blobs"
-    params := url.Values{}
+    return c.StoreWithContext(context.Background(), data, opts)
+}
 
-    if opts != nil {
-        if opts.Epochs > 0 {
-            params.Add("epochs", strconv.Itoa(opts.Epochs))
-        }
+// StoreWithContext is like Store but carries ctx through the request and the retry loop
+func (c *Client) StoreWithContext(ctx context.Context, data []byte, opts *StoreOptions) (*StoreResponse, error) {
+    return c.storeFromReader(ctx, bytes.NewReader(data), int64(len(data)), opts)
+}
 
-        if opts.Deletable {
-            params.Add("deletable", "true")
-        }
+// StoreFromReader stores data from an io.Reader and returns the complete store response.
+// If reader is an io.ReadSeeker (such as *os.File or *bytes.Reader) the upload is streamed and
+// rewound between retries. Any other reader is buffered in memory so that it can be retried,
+// up to MaxUnknownLengthUploadSize; larger content fails with ErrUploadTooLarge, use
+// StoreFromReaderWithLength to stream it.
+func (c *Client) StoreFromReader(reader io.Reader, opts *StoreOptions) (*StoreResponse, error) {
+    return c.StoreFromReaderWithContext(context.Background(), reader, opts)
+}
 
-        if opts.SendObjectTo != "" {
-            params.Add("send_object_to", opts.SendObjectTo)
-        }
+// StoreFromReaderWithContext is like StoreFromReader but carries ctx through the request and the retry loop
+func (c *Client) StoreFromReaderWithContext(ctx context.Context, reader io.Reader, opts *StoreOptions) (*StoreResponse, error) {
+    return c.storeFromReader(ctx, reader, -1, opts)
+}
+
+// StoreFromReaderWithLength stores exactly size bytes read from reader. The upload is streamed
+// with an accurate Content-Length and is not subject to MaxUnknownLengthUploadSize. A negative
+// size means the length is unknown, which behaves like StoreFromReader.
+func (c *Client) StoreFromReaderWithLength(reader io.Reader, size int64, opts *StoreOptions) (*StoreResponse, error) {
+    return c.StoreFromReaderWithLengthWithContext(context.Background(), reader, size, opts)
+}
 
+// StoreFromReaderWithLengthWithContext is like StoreFromReaderWithLength but carries ctx through the request and the retry loop
+func (c *Client) StoreFromReaderWithLengthWithContext(ctx context.Context, reader io.Reader, size int64, opts *StoreOptions) (*StoreResponse, error) {
+    return c.storeFromReader(ctx, reader, size, opts)
+}
+
+// storeFromReader uploads reader to the publisher. size is the number of bytes the reader will
+// produce, or -1 if unknown.
+func (c *Client) storeFromReader(ctx context.Context, reader io.Reader, size int64, opts *StoreOptions) (*StoreResponse, error) {
+    if err := c.checkAttributes(opts); err != nil {
+        return nil, err
     }
 
-    if encoded := params.Encode(); encoded != "" {
-        urlStr += "?" + encoded
+    // Without a known length, the content has to be buffered so it can be measured and replayed
+    if size < 0 {
+        if _, _, ok := rewindableSource(reader); !ok {
+            var err error
+            if reader, err = c.bufferUnknownLength(reader); err != nil {
+                return nil, err
+            }
+        }
     }
 
-    var reader io.Reader = bytes.NewReader(data)
+    urlStr := "/v1/blobs"
+    params := c.storeParams(opts)
 
-    // If encryption is enabled
-    if opts != nil && opts.Encryption != nil {
-        cipher, err := opts.Encryption.getCipher()
-        if err != nil {
-            return nil, fmt.Errorf("failed to create cipher: %w", err)
-        }
+    var encOpts *EncryptionOptions
+    var progress ProgressFunc
+    if opts != nil {
+        encOpts = opts.Encryption
+        progress = opts.Progress
+    }
 
-        var buf bytes.Buffer
-        if err := cipher.EncryptStream(bytes.NewReader(data), &buf); err != nil {
-            return nil, fmt.Errorf("failed to encrypt data: %w", err)
+    // The blob ID of encrypted content can't be known up front, since every encryption is unique
+    var expectedID string
+    if encOpts == nil {
+        var err error
+        if expectedID, err = c.precomputeBlobID(reader, size); err != nil {
+            return nil, err
         }
-        reader = &buf
     }
 
-    req, err := http.NewRequest("PUT", urlStr, reader)
+    if encoded := params.Encode(); encoded != "" {
+        urlStr += "?" + encoded
+    }
+
+    req, err := newUploadRequest(ctx, http.MethodPut, urlStr, reader, size, encOpts, progress)
     if err != nil {
         return nil, err
     }
 
     req.Header.Set("Content-Type", "application/octet-stream")
 
-    resp, err := c.doWithRetry(req, c.PublisherURL)
+    resp, err := c.doWithRetry(ctx, req, c.PublisherURL)
     if err != nil {
         return nil, err
     }
@@ -291,17 +412,20 @@ func (c *Client) Store(data []byte, opts *StoreOptions) (*StoreResponse, error)
     }
     storeResp.NormalizeBlobResponse()
 
-    return &storeResp, nil
+    if expectedID != "" && storeResp.Blob.BlobID != expectedID {
+        return nil, fmt.Errorf("%w: computed %s, publisher returned %s", ErrBlobIDMismatch, expectedID, storeResp.Blob.BlobID)
+    }
+    return c.applyAttributes(ctx, &storeResp, opts)
 }
 
-// StoreFromReader stores data from an io.Reader and returns the complete store response
-func (c *Client) StoreFromReader(reader io.Reader, opts *StoreOptions) (*StoreResponse, error) {
-    urlStr := "/v1/blobs"
+// storeParams returns the query parameters of a store request with opts
+func (c *Client) storeParams(opts *StoreOptions) url.Values {
     params := url.Values{}
 
+    epochs := c.defaultEpochs
     if opts != nil {
         if opts.Epochs > 0 {
-            params.Add("epochs", strconv.Itoa(opts.Epochs))
+            epochs = opts.Epochs
         }
 
         if opts.Deletable {
@@ -311,59 +435,167 @@ func (c *Client) StoreFromReader(reader io.Reader, opts *StoreOptions) (*StoreRe
         if opts.SendObjectTo != "" {
             params.Add("send_object_to", opts.SendObjectTo)
         }
-
     }
-
-    if encoded := params.Encode(); encoded != "" {
-        urlStr += "?" + encoded
+    if epochs > 0 {
+        params.Add("epochs", strconv.Itoa(epochs))
     }
+    return params
+}
 
-    var err error
+// bufferUnknownLength reads reader into memory, failing with ErrUploadTooLarge once it grows
+// past MaxUnknownLengthUploadSize
+func (c *Client) bufferUnknownLength(reader io.Reader) (*bytes.Reader, error) {
+    limit := c.MaxUnknownLengthUploadSize
+    data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read upload content: %w", err)
+    }
+    if int64(len(data)) > limit {
+        return nil, fmt.Errorf("%w: content of unknown length exceeds %d bytes, use StoreFromReaderWithLength to stream it", ErrUploadTooLarge, limit)
+    }
+    return bytes.NewReader(data), nil
+}
 
-    // If encryption is enabled
-    if opts != nil && opts.Encryption != nil {
-        cipher, err := opts.Encryption.getCipher()
+// newUploadRequest creates a request whose body streams src, encrypting it on the fly when
+// encOpts is set. If src can be rewound, GetBody is set so doWithRetry can replay the upload
+// without holding it in memory. size is the number of bytes src will produce, or -1 if unknown.
+// progress, if not nil, is reported the plaintext read by each attempt.
+func newUploadRequest(ctx context.Context, method, urlStr string, src io.Reader, size int64, encOpts *EncryptionOptions, progress ProgressFunc) (*http.Request, error) {
+    var cipher encryption.ContentCipher
+    if encOpts != nil {
+        var err error
+        cipher, err = encOpts.getCipher()
         if err != nil {
             return nil, fmt.Errorf("failed to create cipher: %w", err)
         }
+    }
 
-        var buf bytes.Buffer
-        if err := cipher.EncryptStream(reader, &buf); err != nil {
-            return nil, fmt.Errorf("failed to encrypt data: %w", err)
+    open, remaining, replayable := rewindableSource(src)
+    if replayable {
+        src = open()
+        if size < 0 {
+            size = remaining
         }
-        reader = &buf
     }
 
-    // Create request with the proper reader
-    req, err := http.NewRequest("PUT", urlStr, reader)
+    newBody := func(r io.Reader) io.ReadCloser {
+        if size >= 0 {
+            r = io.LimitReader(r, size)
+        }
+        r = newProgressReader(r, size, progress)
+        if cipher == nil {
+            return io.NopCloser(r)
+        }
+        return encryptingReader(cipher, r)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, method, urlStr, newBody(src))
     if err != nil {
         return nil, err
     }
 
-    req.Header.Set("Content-Type", "application/octet-stream")
+    // The encrypted size differs from the plaintext size, so encrypted uploads are chunked
+    req.ContentLength = -1
+    if cipher == nil && size >= 0 {
+        req.ContentLength = size
+        if size == 0 {
+            req.Body = http.NoBody
+        }
+    }
 
-    resp, err := c.doWithRetry(req, c.PublisherURL)
-    if err != nil {
-        return nil, err
+    if replayable {
+        req.GetBody = func() (io.ReadCloser, error) {
+            return newBody(open()), nil
+        }
     }
-    defer resp.Body.Close()
 
-    respData, err := io.ReadAll(resp.Body)
+    return req, nil
+}
+
+// rewindableSource reports whether src can be read again from its current offset. If so, open
+// returns a fresh reader positioned at that offset and remaining is the number of bytes left.
+func rewindableSource(src io.Reader) (open func() io.Reader, remaining int64, ok bool) {
+    seeker, isSeeker := src.(io.Seeker)
+    if !isSeeker {
+        return nil, 0, false
+    }
+
+    start, err := seeker.Seek(0, io.SeekCurrent)
     if err != nil {
-        return nil, err
+        return nil, 0, false
+    }
+    end, err := seeker.Seek(0, io.SeekEnd)
+    if err != nil {
+        return nil, 0, false
+    }
+    if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+        return nil, 0, false
     }
+    remaining = end - start
 
-    var storeResp StoreResponse
-    if err := json.Unmarshal(respData, &storeResp); err != nil {
-        return nil, fmt.Errorf("failed to parse response: %w", err)
+    // Prefer independent section readers, since the transport may still be reading the
+    // previous attempt's body when the next attempt starts
+    if ra, isReaderAt := src.(io.ReaderAt); isReaderAt {
+        return func() io.Reader {
+            return io.NewSectionReader(ra, start, remaining)
+        }, remaining, true
     }
-    storeResp.NormalizeBlobResponse()
-    return &storeResp, nil
+
+    return func() io.Reader {
+        seeker.Seek(start, io.SeekStart)
+        return src
+    }, remaining, true
+}
+
+// encryptingReader returns a reader producing the ciphertext of src, encrypting through a pipe
+// so that the plaintext is never buffered in full
+func encryptingReader(cipher encryption.ContentCipher, src io.Reader) io.ReadCloser {
+    pr, pw := io.Pipe()
+    go func() {
+        if err := cipher.EncryptStream(src, pw); err != nil {
+            pw.CloseWithError(fmt.Errorf("failed to encrypt data: %w", err))
+            return
+        }
+        pw.Close()
+    }()
+    return pr
+}
+
+// decryptReadCloser is the plaintext side of a decrypting pipe; closing it also closes the
+// ciphertext source
+type decryptReadCloser struct {
+    *io.PipeReader
+    src io.Closer
+}
+
+func (r *decryptReadCloser) Close() error {
+    r.PipeReader.Close()
+    return r.src.Close()
 }
 
-// StoreFromURL downloads and stores content from URL and returns the complete store response
+// decryptingReader returns a reader producing the plaintext of src, decrypting through a pipe
+// so that memory use stays bounded regardless of the blob size
+func decryptingReader(cipher encryption.ContentCipher, src io.ReadCloser) io.ReadCloser {
+    pr, pw := io.Pipe()
+    go func() {
+        if err := cipher.DecryptStream(src, pw); err != nil {
+            pw.CloseWithError(fmt.Errorf("failed to decrypt data: %w", err))
+            return
+        }
+        pw.Close()
+    }()
+    return &decryptReadCloser{PipeReader: pr, src: src}
+}
+
+// StoreFromURL downloads and stores content from URL and returns the complete store response.
+// The download is spooled to a temporary file, so failed upload attempts can be retried.
 func (c *Client) StoreFromURL(sourceURL string, opts *StoreOptions) (*StoreResponse, error) {
-    req, err := http.NewRequest("GET", sourceURL, nil)
+    return c.StoreFromURLWithContext(context.Background(), sourceURL, opts)
+}
+
+// StoreFromURLWithContext is like StoreFromURL but uses ctx for both the download and the upload
+func (c *Client) StoreFromURLWithContext(ctx context.Context, sourceURL string, opts *StoreOptions) (*StoreResponse, error) {
+    req, err := http.NewRequestWithContext(ctx, "GET", sourceURL, nil)
     if err != nil {
         return nil, fmt.Errorf("failed to create request: %w", err)
     }
@@ -378,93 +610,133 @@ func (c *Client) StoreFromURL(sourceURL string, opts *StoreOptions) (*StoreRespo
         return nil, fmt.Errorf("failed to download from URL %s: HTTP request returned status code %d, expected 200 OK", sourceURL, resp.StatusCode)
     }
 
-    return c.StoreFromReader(resp.Body, opts)
+    // The download can't be replayed, so it is spooled to a temporary file that failed upload
+    // attempts are retried from
+    file, err := spoolToTempFile(resp.Body)
+    if err != nil {
+        return nil, fmt.Errorf("failed to download from URL %s: %w", sourceURL, err)
+    }
+    defer os.Remove(file.Name())
+    defer file.Close()
+
+    return c.storeFromReader(ctx, file, -1, opts)
+}
+
+// spoolToTempFile copies r into a new temporary file positioned at its start. The caller must
+// close and remove the file.
+func spoolToTempFile(r io.Reader) (*os.File, error) {
+    file, err := os.CreateTemp("", "walrus-upload-*")
+    if err != nil {
+        return nil, fmt.Errorf("failed to create temporary file: %w", err)
+    }
+    _, err = io.Copy(file, r)
+    if err == nil {
+        _, err = file.Seek(0, io.SeekStart)
+    }
+    if err != nil {
+        file.Close()
+        os.Remove(file.Name())
+        return nil, err
+    }
+    return file, nil
 }
 
 // StoreFile stores a file and returns the complete store response
 func (c *Client) StoreFile(filePath string, opts *StoreOptions) (*StoreResponse, error) {
+    return c.StoreFileWithContext(context.Background(), filePath, opts)
+}
+
+// StoreFileWithContext is like StoreFile but carries ctx through the request and the retry loop
+func (c *Client) StoreFileWithContext(ctx context.Context, filePath string, opts *StoreOptions) (*StoreResponse, error) {
     file, err := os.Open(filePath)
     if err != nil {
         return nil, err
     }
     defer file.Close()
 
-    return c.StoreFromReader(file, opts)
+    return c.StoreFromReaderWithContext(ctx, file, opts)
 }
 
-// Read retrieves a blob from the Walrus Aggregator
+// Read retrieves a blob from the Walrus Aggregator.
+// With ReadOptions.Verify, content that fails verification is fetched again from another aggregator.
 func (c *Client) Read(blobID string, opts *ReadOptions) ([]byte, error) {
-    urlStr := fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID))
+    return c.ReadWithContext(context.Background(), blobID, opts)
+}
 
-    req, err := http.NewRequest(http.MethodGet, urlStr, nil)
+// ReadWithContext is like Read but carries ctx through the request and the retry loop
+func (c *Client) ReadWithContext(ctx context.Context, blobID string, opts *ReadOptions) ([]byte, error) {
+    data, _, err := c.readContent(ctx, fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID)), opts)
+    return data, err
+}
+
+// readContent reads the content at urlStr from the aggregators and returns it with the metadata
+// of the response
+func (c *Client) readContent(ctx context.Context, urlStr string, opts *ReadOptions) ([]byte, *BlobMetadata, error) {
+    if opts != nil && opts.Verify != nil {
+        return c.readVerifiedBytes(ctx, urlStr, opts)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
     if err != nil {
-        return nil, err
+        return nil, nil, err
     }
 
-    resp, err := c.doWithRetry(req, c.AggregatorURL)
+    resp, err := c.doRead(ctx, req)
     if err != nil {
-        return nil, err
+        return nil, nil, err
     }
     defer resp.Body.Close()
+    resp.Body = withProgress(resp.Body, resp.ContentLength, opts.progress())
+    metadata := c.blobMetadata(resp)
 
     // If decryption is enabled
     if opts != nil && opts.Encryption != nil {
         cipher, err := opts.Encryption.getCipher()
         if err != nil {
-            return nil, fmt.Errorf("failed to create cipher: %w", err)
+            return nil, nil, fmt.Errorf("failed to create cipher: %w", err)
         }
 
         var decryptedBuf bytes.Buffer
         if err := cipher.DecryptStream(resp.Body, &decryptedBuf); err != nil {
-            return nil, fmt.Errorf("failed to decrypt data: %w", err)
+            return nil, nil, fmt.Errorf("failed to decrypt data: %w", err)
         }
-        return decryptedBuf.Bytes(), nil
+        return decryptedBuf.Bytes(), metadata, nil
     }
 
-    return io.ReadAll(resp.Body)
-}
-
-// ReadToFile retrieves a blob and writes it to a file
-func (c *Client) ReadToFile(blobID, filePath string, opts *ReadOptions) error {
-    urlStr := fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID))
-
-    req, err := http.NewRequest(http.MethodGet, urlStr, nil)
+    data, err := io.ReadAll(resp.Body)
     if err != nil {
-        return err
+        return nil, nil, err
     }
+    return data, metadata, nil
+}
 
-    resp, err := c.doWithRetry(req, c.AggregatorURL)
-    if err != nil {
-        return err
-    }
-    defer resp.Body.Close()
-
-    // Create the file
-    outFile, err := os.Create(filePath)
-    if err != nil {
-        return err
-    }
-    defer outFile.Close()
+// ReadToFile retrieves a blob and writes it to a file.
+// The blob is downloaded into filePath+"."+blobID+".part", resuming from where it stopped if
+// the response breaks off, and only moved to filePath once complete. If the download fails, the
+// .part file is kept and a later call for the same blob and filePath resumes from its end.
+// With ReadOptions.Verify, the file is only written once the content has been verified.
+func (c *Client) ReadToFile(blobID, filePath string, opts *ReadOptions) error {
+    return c.ReadToFileWithContext(context.Background(), blobID, filePath, opts)
+}
 
-    // If decryption is enabled
-    if opts != nil && opts.Encryption != nil {
-        cipher, err := opts.Encryption.getCipher()
-        if err != nil {
-            return fmt.Errorf("failed to create cipher: %w", err)
-        }
-        return cipher.DecryptStream(resp.Body, outFile)
+// ReadToFileWithContext is like ReadToFile but carries ctx through the request and the retry loop
+func (c *Client) ReadToFileWithContext(ctx context.Context, blobID, filePath string, opts *ReadOptions) error {
+    if opts != nil && opts.Verify != nil {
+        return c.readVerifiedToFile(ctx, blobID, filePath, opts)
     }
-
-    // Write the response body to the file
-    _, err = io.Copy(outFile, resp.Body)
-    return err
+    return c.readToFile(ctx, blobID, filePath, opts)
 }
 
 // GetAPISpec retrieves the API specification from the aggregator or publisher
 func (c *Client) GetAPISpec(isAggregator bool) ([]byte, error) {
+    return c.GetAPISpecWithContext(context.Background(), isAggregator)
+}
+
+// GetAPISpecWithContext is like GetAPISpec but carries ctx through the request and the retry loop
+func (c *Client) GetAPISpecWithContext(ctx context.Context, isAggregator bool) ([]byte, error) {
     urlStr := "/v1/api"
 
-    req, err := http.NewRequest(http.MethodGet, urlStr, nil)
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
     if err != nil {
         return nil, err
     }
@@ -474,7 +746,7 @@ func (c *Client) GetAPISpec(isAggregator bool) ([]byte, error) {
         urls = c.AggregatorURL
     }
 
-    resp, err := c.doWithRetry(req, urls)
+    resp, err := c.doWithRetry(ctx, req, urls)
     if err != nil {
         return nil, err
     }
@@ -485,117 +757,231 @@ func (c *Client) GetAPISpec(isAggregator bool) ([]byte, error) {
 
 // Head retrieves blob metadata from the Walrus Aggregator without downloading the content
 func (c *Client) Head(blobID string) (*BlobMetadata, error) {
-    urlStr := fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID))
+    return c.HeadWithContext(context.Background(), blobID)
+}
+
+// HeadWithContext is like Head but carries ctx through the request and the retry loop
+func (c *Client) HeadWithContext(ctx context.Context, blobID string) (*BlobMetadata, error) {
+    return c.head(ctx, fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID)))
+}
 
-    req, err := http.NewRequest(http.MethodHead, urlStr, nil)
+// head retrieves the metadata of the content at urlStr from the aggregators
+func (c *Client) head(ctx context.Context, urlStr string) (*BlobMetadata, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodHead, urlStr, nil)
     if err != nil {
         return nil, fmt.Errorf("failed to create HEAD request: %w", err)
     }
 
-    resp, err := c.doWithRetry(req, c.AggregatorURL)
+    resp, err := c.doRead(ctx, req)
     if err != nil {
         return nil, err
     }
     defer resp.Body.Close()
 
-    metadata := &BlobMetadata{
-        ContentLength: resp.ContentLength,
-        ContentType:   resp.Header.Get("Content-Type"),
-        LastModified:  resp.Header.Get("Last-Modified"),
-        ETag:          resp.Header.Get("ETag"),
-    }
-
-    return metadata, nil
+    return c.blobMetadata(resp), nil
 }
 
-// ReadToReader retrieves a blob and writes it to the provided io.Writer
+// ReadToReader retrieves a blob and returns a stream of its content.
+// When decryption is enabled the content is decrypted on the fly as the stream is read, so
+// authentication failures surface as read errors. The caller must close the stream.
+// With ReadOptions.Verify, a mismatch is reported as ErrContentMismatch at the end of the
+// stream instead of io.EOF; the read is not retried.
 func (c *Client) ReadToReader(blobID string, opts *ReadOptions) (io.ReadCloser, error) {
-    urlStr := fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID))
+    return c.ReadToReaderWithContext(context.Background(), blobID, opts)
+}
 
-    req, err := http.NewRequest(http.MethodGet, urlStr, nil)
+// ReadToReaderWithContext is like ReadToReader but carries ctx through the request and the retry loop.
+// Cancelling ctx also aborts reads from the returned stream.
+func (c *Client) ReadToReaderWithContext(ctx context.Context, blobID string, opts *ReadOptions) (io.ReadCloser, error) {
+    body, _, err := c.readStream(ctx, fmt.Sprintf("/v1/blobs/%s", url.PathEscape(blobID)), opts)
+    return body, err
+}
+
+// readStream requests the content at urlStr from the aggregators and returns a stream of it
+// with the metadata of the response
+func (c *Client) readStream(ctx context.Context, urlStr string, opts *ReadOptions) (io.ReadCloser, *BlobMetadata, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
     if err != nil {
-        return nil, err
+        return nil, nil, err
     }
 
-    resp, err := c.doWithRetry(req, c.AggregatorURL)
+    resp, err := c.doRead(ctx, req)
     if err != nil {
-        return nil, err
+        return nil, nil, err
+    }
+    metadata := c.blobMetadata(resp)
+
+    resp.Body = withProgress(resp.Body, resp.ContentLength, opts.progress())
+
+    // The stream can only be verified once it has been read to the end
+    if opts != nil && opts.Verify != nil {
+        resp.Body = &verifyingReadCloser{ReadCloser: resp.Body, verifier: newContentVerifier(opts.Verify)}
     }
 
     // If decryption is enabled
     if opts != nil && opts.Encryption != nil {
         cipher, err := opts.Encryption.getCipher()
         if err != nil {
-            return nil, fmt.Errorf("failed to create cipher: %w", err)
+            resp.Body.Close()
+            return nil, nil, fmt.Errorf("failed to create cipher: %w", err)
         }
 
-        var decryptedBuf bytes.Buffer
-        if err := cipher.DecryptStream(resp.Body, &decryptedBuf); err != nil {
-            return nil, fmt.Errorf("failed to decrypt data: %w", err)
-        }
-        return io.NopCloser(&decryptedBuf), nil
+        return decryptingReader(cipher, resp.Body), metadata, nil
     }
 
-    return resp.Body, nil
+    return resp.Body, metadata, nil
 }
 
-// doWithRetry performs an HTTP request with retry logic
-func (c *Client) doWithRetry(req *http.Request, urls []string) (*http.Response, error) {
-    var lastErr error
-    // Calculate total attempts based on retry config and URL count
-    totalAttempts := c.retryConfig.MaxRetries + 1
-    attemptCount := 0
+// doWithRetry performs an HTTP request with retry logic.
+// The request URL must be relative; it is resolved against each base URL in turn, starting
+// with the healthiest and fastest endpoint according to the client's endpoint pool.
+// Whether and when failed attempts are retried is decided by the client's RetryPolicy.
+// Cancelling ctx aborts both in-flight attempts and the delay between them.
+// If the request fails, the returned error is a *MultiError holding an *APIError per attempt.
+func (c *Client) doWithRetry(ctx context.Context, req *http.Request, urls []string) (*http.Response, error) {
+    resp, _, err := c.doWithRetryFrom(ctx, req, urls)
+    return resp, err
+}
+
+// doWithRetryFrom is like doWithRetry but also returns the base URL of the endpoint that served
+// the response
+func (c *Client) doWithRetryFrom(ctx context.Context, req *http.Request, urls []string) (*http.Response, string, error) {
+    var attemptErrs []error
+    policy := c.getRetryPolicy()
+    if len(urls) == 0 {
+        return nil, "", fmt.Errorf("no endpoints configured")
+    }
+
+    path := req.URL.String()
+    start := time.Now()
+    urls = c.endpoints.order(urls)
+
+    // Try URLs in round-robin fashion until the policy gives up
+    for attempt := 1; ; attempt++ {
+        if err := ctx.Err(); err != nil {
+            return nil, "", fmt.Errorf("request canceled: %w", err)
+        }
 
-    // Try URLs in round-robin fashion until max retries reached
-    for attemptCount < totalAttempts {
         // Get URL index for this attempt
-        urlIndex := attemptCount % len(urls)
+        urlIndex := (attempt - 1) % len(urls)
         baseURL := urls[urlIndex]
 
-        // Update request URL with current base URL
-        req.URL.Host = ""
-        req.URL.Scheme = ""
-        fullURL := baseURL + req.URL.String()
-        req.URL, _ = url.Parse(fullURL)
-
-        // Create a new request for this attempt (since the original body might have been consumed)
-        newReq := &http.Request{}
-        *newReq = *req
-        if req.Body != nil {
-            bodyBytes, err := io.ReadAll(req.Body)
+        fullURL, err := url.Parse(baseURL + path)
+        if err != nil {
+            return nil, "", fmt.Errorf("invalid endpoint URL %s: %w", baseURL, err)
+        }
+
+        // Create a new request for this attempt. The first attempt streams the original body;
+        // later attempts need a fresh copy from GetBody since the previous one was consumed.
+        newReq := req.Clone(ctx)
+        newReq.URL = fullURL
+        newReq.Host = ""
+        if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+            if req.GetBody == nil {
+                return nil, "", fmt.Errorf("request body cannot be replayed after a failed attempt: %w", &MultiError{Errors: attemptErrs})
+            }
+            body, err := req.GetBody()
             if err != nil {
-                return nil, fmt.Errorf("failed to read request body: %w", err)
+                return nil, "", fmt.Errorf("failed to rewind request body: %w", err)
             }
-            req.Body.Close()
-            newReq.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-            req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+            newReq.Body = body
         }
 
-        resp, err := c.httpClient.Do(newReq)
-        if err == nil && resp.StatusCode == http.StatusOK {
-            return resp, nil
+        attemptStart := time.Now()
+        resp, err := c.send(newReq, baseURL)
+        if err != nil && ctx.Err() != nil {
+            return nil, "", fmt.Errorf("request canceled: %w", ctx.Err())
+        }
+        var authErr *authError
+        if errors.As(err, &authErr) {
+            return nil, "", authErr
         }
 
-        if err != nil {
-            lastErr = err
-        } else {
-            // Attempt to read error message from response body for better error reporting
-            errBody, readErr := io.ReadAll(resp.Body)
-            resp.Body.Close()
-            if readErr == nil && len(errBody) > 0 {
-                lastErr = fmt.Errorf("request failed with status code %d: %s", resp.StatusCode, string(errBody))
-            } else {
-                lastErr = fmt.Errorf("request failed with status code %d", resp.StatusCode)
-            }
+        attemptErr := checkResponse(newReq, resp, err)
+        if attemptErr == nil {
+            c.endpoints.recordSuccess(baseURL, time.Since(attemptStart))
+            return resp, baseURL, nil
+        }
+        attemptErr.URL = fullURL.String()
+        attemptErr.Attempt = attempt
+        attemptErrs = append(attemptErrs, attemptErr)
+
+        // Only failures that point at the endpoint count against its health; a 404 for
+        // example says nothing about whether the endpoint works
+        if IsRetryable(attemptErr) {
+            c.endpoints.recordFailure(baseURL, attemptErr)
         }
 
-        // Sleep before next attempt if not the last attempt
-        if attemptCount < totalAttempts-1 {
-            time.Sleep(c.retryConfig.RetryDelay)
+        delay, retry := policy.NextDelay(attempt, time.Since(start), attemptErr)
+        if !retry {
+            return nil, "", &MultiError{Errors: attemptErrs}
+        }
+        if c.retryHook != nil {
+            c.retryHook(RetryEvent{Attempt: attempt, Err: attemptErr, Delay: delay})
         }
 
-        attemptCount++
+        if err := sleepWithContext(ctx, delay); err != nil {
+            return nil, "", fmt.Errorf("request canceled after %d attempts (last error: %v): %w", attempt, attemptErr, err)
+        }
     }
+}
+
+// getRetryPolicy returns the configured retry policy, or one derived from the retry configuration
+func (c *Client) getRetryPolicy() RetryPolicy {
+    if c.retryPolicy != nil {
+        return c.retryPolicy
+    }
+    return NewDefaultRetryPolicy(c.retryConfig.MaxRetries, c.retryConfig.RetryDelay)
+}
 
-    return nil, fmt.Errorf("all retry attempts failed: %w", lastErr)
+// checkResponse turns the outcome of one attempt into an *APIError, or nil if resp is usable.
+// The body of an unusable response is read into the error and closed.
+func checkResponse(req *http.Request, resp *http.Response, err error) *APIError {
+    if err != nil {
+        // The url.Error from the client repeats the method and URL already in APIError
+        var urlErr *url.Error
+        if errors.As(err, &urlErr) {
+            err = urlErr.Err
+        }
+        return &APIError{Err: err}
+    }
+
+    switch resp.StatusCode {
+    case http.StatusOK:
+        return nil
+    case http.StatusPartialContent:
+        // A partial response is only usable if it covers the range that was asked for
+        rangeErr := checkPartialContent(req, resp)
+        if rangeErr == nil {
+            return nil
+        }
+        resp.Body.Close()
+        return &APIError{StatusCode: resp.StatusCode, Err: rangeErr}
+    }
+
+    // Attempt to read error message from response body for better error reporting
+    errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+    resp.Body.Close()
+    return &APIError{
+        StatusCode: resp.StatusCode,
+        Body:       string(errBody),
+        RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+    }
+}
+
+// sleepWithContext waits for d or until ctx is done, whichever comes first
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+    if d <= 0 {
+        return ctx.Err()
+    }
+
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
 }
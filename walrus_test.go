@@ -2,8 +2,10 @@ package walrus_go
 
 import (
     "bytes"
+    "context"
     "crypto/sha256"
     "encoding/json"
+    "errors"
     "fmt"
     "io"
     "math/rand"
@@ -15,15 +17,31 @@ import (
     "time"
 
     "github.com/namihq/walrus-go/encryption"
+    "github.com/namihq/walrus-go/walrustest"
 )
 
 const (
     testContent = "Hello, Walrus!"
 )
 
-// Helper function to create a test client
+// Helper function to create a test client. Tests run against an in-process fake unless
+// WALRUS_LIVE_TESTS is set, in which case they use the public testnet.
 func newTestClient(t *testing.T) *Client {
-    return NewClient()
+    if liveTests() {
+        return NewClient()
+    }
+
+    server := walrustest.NewServer()
+    t.Cleanup(server.Close)
+    return NewClient(
+        WithAggregatorURLs([]string{server.URL}),
+        WithPublisherURLs([]string{server.URL}),
+    )
+}
+
+// liveTests reports whether tests should run against the public testnet
+func liveTests() bool {
+    return os.Getenv("WALRUS_LIVE_TESTS") != ""
 }
 
 // Helper function to store test content and return blobID
@@ -126,6 +144,13 @@ func TestStoreFromReaderWihoutContentLength(t *testing.T) {
 func TestStoreFromURL(t *testing.T) {
     client := newTestClient(t)
     testURL := "https://raw.githubusercontent.com/namihq/walrus-go/main/README.md"
+    if !liveTests() {
+        source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            http.ServeFile(w, r, "README.md")
+        }))
+        defer source.Close()
+        testURL = source.URL + "/README.md"
+    }
 
     resp, err := client.StoreFromURL(testURL, &StoreOptions{Epochs: 1})
     if err != nil {
@@ -138,6 +163,31 @@ func TestStoreFromURL(t *testing.T) {
     }
 }
 
+// TestStoreFromURLRetry tests that uploads of downloaded content are retried
+func TestStoreFromURLRetry(t *testing.T) {
+    content := bytes.Repeat([]byte("downloaded "), 1024)
+    source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write(content)
+    }))
+    defer source.Close()
+
+    server := walrustest.NewServer()
+    defer server.Close()
+    server.InjectFault(walrustest.Fault{Method: http.MethodPut, StatusCode: http.StatusServiceUnavailable, Times: 2})
+
+    client := NewClient(
+        WithRetryConfig(3, 10*time.Millisecond),
+        WithPublisherURLs([]string{server.URL}),
+    )
+    resp, err := client.StoreFromURL(source.URL, nil)
+    if err != nil {
+        t.Fatalf("Expected successful store after retries, got error: %v", err)
+    }
+    if stored, ok := server.Blob(resp.Blob.BlobID); !ok || !bytes.Equal(stored, content) {
+        t.Errorf("Expected the downloaded content to be stored, got %d bytes", len(stored))
+    }
+}
+
 // TestStoreFile tests storing a file
 func TestStoreFile(t *testing.T) {
     client := newTestClient(t)
@@ -469,6 +519,419 @@ func TestRequestBodyPreservation(t *testing.T) {
     }
 }
 
+// TestContextCancellation tests that a cancelled context aborts the retry loop
+func TestContextCancellation(t *testing.T) {
+    attemptCount := 0
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        attemptCount++
+        w.WriteHeader(http.StatusInternalServerError)
+    }))
+    defer server.Close()
+
+    // A long retry delay means the test only finishes quickly if the sleep is interrupted
+    client := NewClient(
+        WithRetryConfig(5, time.Minute),
+        WithAggregatorURLs([]string{server.URL}),
+    )
+
+    ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+    defer cancel()
+
+    start := time.Now()
+    _, err := client.ReadWithContext(ctx, "test-blob", nil)
+    if err == nil {
+        t.Fatal("Expected error from cancelled context, got none")
+    }
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("Expected error wrapping context.DeadlineExceeded, got: %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > 5*time.Second {
+        t.Errorf("Retry delay was not interrupted by context cancellation, took %v", elapsed)
+    }
+    if attemptCount != 1 {
+        t.Errorf("Expected 1 attempt before cancellation, got %d", attemptCount)
+    }
+}
+
+// TestContextCancelledBeforeRequest tests that no request is sent with an already cancelled context
+func TestContextCancelledBeforeRequest(t *testing.T) {
+    attemptCount := 0
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        attemptCount++
+        w.WriteHeader(http.StatusOK)
+    }))
+    defer server.Close()
+
+    client := NewClient(WithPublisherURLs([]string{server.URL}))
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    _, err := client.StoreWithContext(ctx, []byte(testContent), nil)
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Expected error wrapping context.Canceled, got: %v", err)
+    }
+    if attemptCount != 0 {
+        t.Errorf("Expected no requests to be sent, got %d", attemptCount)
+    }
+}
+
+// TestStoreFileStreamingRetry tests that file uploads carry a Content-Length and are rewound between retries
+func TestStoreFileStreamingRetry(t *testing.T) {
+    attemptCount := 0
+    maxAttempts := 3
+    content := bytes.Repeat([]byte("streaming upload "), 4096)
+
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        attemptCount++
+        if r.ContentLength != int64(len(content)) {
+            t.Errorf("Expected Content-Length %d, got %d", len(content), r.ContentLength)
+        }
+        body, err := io.ReadAll(r.Body)
+        if err != nil {
+            t.Errorf("Failed to read request body: %v", err)
+        }
+        if !bytes.Equal(body, content) {
+            t.Errorf("Attempt %d received %d bytes, expected the full %d byte file", attemptCount, len(body), len(content))
+        }
+
+        if attemptCount < maxAttempts {
+            w.WriteHeader(http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(StoreResponse{
+            Blob: BlobInfo{BlobID: "test-id", EndEpoch: 100},
+        })
+    }))
+    defer server.Close()
+
+    tmpfile, err := os.CreateTemp("", "walrus-stream-test-*.bin")
+    if err != nil {
+        t.Fatalf("Failed to create temporary test file: %v", err)
+    }
+    defer os.Remove(tmpfile.Name())
+    if _, err := tmpfile.Write(content); err != nil {
+        t.Fatalf("Failed to write test content to temporary file: %v", err)
+    }
+    tmpfile.Close()
+
+    client := NewClient(
+        WithRetryConfig(maxAttempts, 10*time.Millisecond),
+        WithPublisherURLs([]string{server.URL}),
+    )
+
+    if _, err := client.StoreFile(tmpfile.Name(), nil); err != nil {
+        t.Fatalf("Expected successful store after retries, got error: %v", err)
+    }
+    if attemptCount != maxAttempts {
+        t.Errorf("Expected %d attempts, got %d", maxAttempts, attemptCount)
+    }
+}
+
+// TestStoreFromReaderNonSeekable tests that a plain reader of known length is streamed once and not retried
+func TestStoreFromReaderNonSeekable(t *testing.T) {
+    attemptCount := 0
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        attemptCount++
+        io.Copy(io.Discard, r.Body)
+        w.WriteHeader(http.StatusInternalServerError)
+    }))
+    defer server.Close()
+
+    client := NewClient(
+        WithRetryConfig(3, 10*time.Millisecond),
+        WithPublisherURLs([]string{server.URL}),
+    )
+
+    // Hide the Seek method of strings.Reader
+    reader := struct{ io.Reader }{strings.NewReader(testContent)}
+
+    _, err := client.StoreFromReaderWithLength(reader, int64(len(testContent)), nil)
+    if err == nil {
+        t.Fatal("Expected error but got none")
+    }
+    if !strings.Contains(err.Error(), "cannot be replayed") {
+        t.Errorf("Expected error about replaying the body, got: %v", err)
+    }
+    if attemptCount != 1 {
+        t.Errorf("Expected 1 attempt, got %d", attemptCount)
+    }
+}
+
+// TestMaxUnknownLengthUploadSize tests that uploads of unknown length are limited
+func TestMaxUnknownLengthUploadSize(t *testing.T) {
+    var received []byte
+    var contentLength int64
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        contentLength = r.ContentLength
+        received, _ = io.ReadAll(r.Body)
+        json.NewEncoder(w).Encode(StoreResponse{
+            Blob: BlobInfo{BlobID: "test-id", EndEpoch: 100},
+        })
+    }))
+    defer server.Close()
+
+    client := NewClient(
+        WithPublisherURLs([]string{server.URL}),
+        WithMaxUnknownLengthUploadSize(1024),
+    )
+
+    // Readers without Seek have an unknown length
+    oversized := struct{ io.Reader }{bytes.NewReader(make([]byte, 2048))}
+    _, err := client.StoreFromReader(oversized, nil)
+    if !errors.Is(err, ErrUploadTooLarge) {
+        t.Fatalf("Expected ErrUploadTooLarge, got: %v", err)
+    }
+    if received != nil {
+        t.Error("Expected no request to be sent for an oversized upload")
+    }
+
+    small := struct{ io.Reader }{strings.NewReader(testContent)}
+    if _, err := client.StoreFromReader(small, nil); err != nil {
+        t.Fatalf("Failed to store data below the limit: %v", err)
+    }
+    if string(received) != testContent {
+        t.Errorf("Expected body %q, got %q", testContent, string(received))
+    }
+    if contentLength != int64(len(testContent)) {
+        t.Errorf("Expected Content-Length %d, got %d", len(testContent), contentLength)
+    }
+}
+
+// TestStoreFromReaderWithLength tests that known-length uploads bypass the unknown length limit
+func TestStoreFromReaderWithLength(t *testing.T) {
+    content := make([]byte, 4096)
+    rand.Read(content)
+
+    var received []byte
+    var contentLength int64
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        contentLength = r.ContentLength
+        received, _ = io.ReadAll(r.Body)
+        json.NewEncoder(w).Encode(StoreResponse{
+            Blob: BlobInfo{BlobID: "test-id", EndEpoch: 100},
+        })
+    }))
+    defer server.Close()
+
+    client := NewClient(
+        WithPublisherURLs([]string{server.URL}),
+        WithMaxUnknownLengthUploadSize(1024),
+    )
+
+    // Trailing bytes past size must not be sent
+    reader := struct{ io.Reader }{io.MultiReader(bytes.NewReader(content), strings.NewReader("trailing"))}
+    if _, err := client.StoreFromReaderWithLength(reader, int64(len(content)), nil); err != nil {
+        t.Fatalf("Failed to store data with known length: %v", err)
+    }
+    if contentLength != int64(len(content)) {
+        t.Errorf("Expected Content-Length %d, got %d", len(content), contentLength)
+    }
+    if !bytes.Equal(received, content) {
+        t.Errorf("Expected %d bytes of content, got %d bytes", len(content), len(received))
+    }
+}
+
+// TestStoreEncryptedStreamingRetry tests that encrypted uploads are re-encrypted from the source on retry
+func TestStoreEncryptedStreamingRetry(t *testing.T) {
+    key := make([]byte, 32)
+    rand.Read(key)
+    content := make([]byte, 100*1024)
+    rand.Read(content)
+
+    attemptCount := 0
+    maxAttempts := 2
+    var uploads [][]byte
+
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        attemptCount++
+        body, _ := io.ReadAll(r.Body)
+        uploads = append(uploads, body)
+
+        if attemptCount < maxAttempts {
+            w.WriteHeader(http.StatusInternalServerError)
+            return
+        }
+        json.NewEncoder(w).Encode(StoreResponse{
+            Blob: BlobInfo{BlobID: "test-id", EndEpoch: 100},
+        })
+    }))
+    defer server.Close()
+
+    client := NewClient(
+        WithRetryConfig(maxAttempts, 10*time.Millisecond),
+        WithPublisherURLs([]string{server.URL}),
+    )
+
+    _, err := client.Store(content, &StoreOptions{
+        Encryption: &EncryptionOptions{Key: key},
+    })
+    if err != nil {
+        t.Fatalf("Expected successful store after retries, got error: %v", err)
+    }
+
+    cipher, err := encryption.NewGCMContentCipher(key)
+    if err != nil {
+        t.Fatalf("Failed to create cipher: %v", err)
+    }
+    for i, upload := range uploads {
+        var decrypted bytes.Buffer
+        if err := cipher.DecryptStream(bytes.NewReader(upload), &decrypted); err != nil {
+            t.Fatalf("Failed to decrypt upload %d: %v", i, err)
+        }
+        if !bytes.Equal(decrypted.Bytes(), content) {
+            t.Errorf("Upload %d does not decrypt to the original content", i)
+        }
+    }
+}
+
+// TestReadToReaderStreamingDecryption tests that ReadToReader decrypts encrypted blobs as they are read
+func TestReadToReaderStreamingDecryption(t *testing.T) {
+    key := make([]byte, 32)
+    rand.Read(key)
+    content := make([]byte, 1024*1024)
+    rand.Read(content)
+
+    cipher, err := encryption.NewGCMContentCipher(key)
+    if err != nil {
+        t.Fatalf("Failed to create cipher: %v", err)
+    }
+    var encrypted bytes.Buffer
+    if err := cipher.EncryptStream(bytes.NewReader(content), &encrypted); err != nil {
+        t.Fatalf("Failed to encrypt test content: %v", err)
+    }
+    tampered := append([]byte(nil), encrypted.Bytes()...)
+    tampered[len(tampered)-1] ^= 0x01
+
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if strings.HasSuffix(r.URL.Path, "/tampered") {
+            w.Write(tampered)
+            return
+        }
+        w.Write(encrypted.Bytes())
+    }))
+    defer server.Close()
+
+    client := NewClient(
+        WithRetryConfig(0, 0),
+        WithAggregatorURLs([]string{server.URL}),
+    )
+    readOpts := &ReadOptions{Encryption: &EncryptionOptions{Key: key}}
+
+    t.Run("full read", func(t *testing.T) {
+        reader, err := client.ReadToReader("blob", readOpts)
+        if err != nil {
+            t.Fatalf("Failed to get reader: %v", err)
+        }
+        defer reader.Close()
+
+        retrieved, err := io.ReadAll(reader)
+        if err != nil {
+            t.Fatalf("Failed to read decrypted stream: %v", err)
+        }
+        if !bytes.Equal(retrieved, content) {
+            t.Error("Decrypted stream doesn't match original content")
+        }
+    })
+
+    t.Run("early close", func(t *testing.T) {
+        reader, err := client.ReadToReader("blob", readOpts)
+        if err != nil {
+            t.Fatalf("Failed to get reader: %v", err)
+        }
+
+        buf := make([]byte, 1024)
+        if _, err := io.ReadFull(reader, buf); err != nil {
+            t.Fatalf("Failed to read from decrypted stream: %v", err)
+        }
+        if !bytes.Equal(buf, content[:1024]) {
+            t.Error("Decrypted prefix doesn't match original content")
+        }
+        if err := reader.Close(); err != nil {
+            t.Errorf("Failed to close stream: %v", err)
+        }
+    })
+
+    t.Run("tampered", func(t *testing.T) {
+        reader, err := client.ReadToReader("tampered", readOpts)
+        if err != nil {
+            t.Fatalf("Failed to get reader: %v", err)
+        }
+        defer reader.Close()
+
+        if _, err := io.ReadAll(reader); err == nil {
+            t.Error("Expected decryption error for tampered content, got none")
+        }
+    })
+}
+
+// TestEnvelopeEncryption tests storing and reading blobs with a KeyProvider
+func TestEnvelopeEncryption(t *testing.T) {
+    blobs := make(map[string][]byte)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.Method {
+        case http.MethodPut:
+            body, _ := io.ReadAll(r.Body)
+            blobID := fmt.Sprintf("blob-%d", len(blobs))
+            blobs[blobID] = body
+            json.NewEncoder(w).Encode(StoreResponse{
+                Blob: BlobInfo{BlobID: blobID, EndEpoch: 100},
+            })
+        case http.MethodGet:
+            w.Write(blobs[strings.TrimPrefix(r.URL.Path, "/v1/blobs/")])
+        }
+    }))
+    defer server.Close()
+
+    client := NewClient(
+        WithPublisherURLs([]string{server.URL}),
+        WithAggregatorURLs([]string{server.URL}),
+    )
+
+    provider, err := encryption.NewMemoryKeyProvider()
+    if err != nil {
+        t.Fatalf("Failed to create key provider: %v", err)
+    }
+    encOpts := &EncryptionOptions{KeyProvider: provider}
+
+    storeAndCheck := func(content []byte) string {
+        resp, err := client.Store(content, &StoreOptions{Encryption: encOpts})
+        if err != nil {
+            t.Fatalf("Failed to store encrypted data: %v", err)
+        }
+        if bytes.Contains(blobs[resp.Blob.BlobID], content) {
+            t.Error("Stored blob contains the plaintext")
+        }
+        return resp.Blob.BlobID
+    }
+
+    first := storeAndCheck([]byte("envelope encrypted before rotation"))
+    if _, err := provider.Rotate(); err != nil {
+        t.Fatalf("Failed to rotate key: %v", err)
+    }
+    second := storeAndCheck([]byte("envelope encrypted after rotation"))
+
+    for blobID, expected := range map[string]string{
+        first:  "envelope encrypted before rotation",
+        second: "envelope encrypted after rotation",
+    } {
+        retrieved, err := client.Read(blobID, &ReadOptions{Encryption: encOpts})
+        if err != nil {
+            t.Fatalf("Failed to read encrypted blob %s: %v", blobID, err)
+        }
+        if string(retrieved) != expected {
+            t.Errorf("Expected %q, got %q", expected, string(retrieved))
+        }
+    }
+
+    _, err = client.Store([]byte(testContent), &StoreOptions{
+        Encryption: &EncryptionOptions{KeyProvider: provider, Suite: encryption.AES256CBC},
+    })
+    if err == nil {
+        t.Error("Expected error for envelope encryption with CBC")
+    }
+}
+
 // TestEncryption tests both CBC and GCM encryption modes
 func TestEncryption(t *testing.T) {
     client := newTestClient(t)
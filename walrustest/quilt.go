@@ -0,0 +1,224 @@
+package walrustest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// quiltMetadataField is the multipart field holding the tags of the patches
+	quiltMetadataField = "_metadata"
+
+	// sha256Size is the size of the fake's raw blob IDs
+	sha256Size = 32
+)
+
+// QuiltPatchID returns the ID the fake assigns to the patch at index in a quilt. Like a real
+// quilt patch ID, it is the quilt ID followed by a version byte and the patch's start and end
+// index, in unpadded URL-safe base64. Patches are indexed in order of their identifiers.
+func QuiltPatchID(quiltID string, index int) string {
+	raw, err := base64.RawURLEncoding.DecodeString(quiltID)
+	if err != nil {
+		return ""
+	}
+	raw = append(raw, 1)
+	raw = binary.LittleEndian.AppendUint16(raw, uint16(index))
+	raw = binary.LittleEndian.AppendUint16(raw, uint16(index+1))
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// parseQuiltPatchID splits a quilt patch ID into the quilt ID and the patch index
+func parseQuiltPatchID(patchID string) (quiltID string, index int, ok bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(patchID)
+	if err != nil || len(raw) != sha256Size+5 || raw[sha256Size] != 1 {
+		return "", 0, false
+	}
+	quiltID = base64.RawURLEncoding.EncodeToString(raw[:sha256Size])
+	return quiltID, int(binary.LittleEndian.Uint16(raw[sha256Size+1:])), true
+}
+
+// QuiltPatch returns the content and tags of a patch of a stored quilt
+func (s *Server) QuiltPatch(quiltID, identifier string) (data []byte, tags map[string]string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.quilts[quiltID] {
+		if p.identifier == identifier {
+			return append([]byte(nil), p.data...), p.tags, true
+		}
+	}
+	return nil, nil, false
+}
+
+func (s *Server) serveStoreQuilt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	epochs, deletable, ok := storeParams(w, r)
+	if !ok {
+		return
+	}
+
+	body, ok := s.readUpload(w, r.Body)
+	if !ok {
+		return
+	}
+
+	patches, err := parseQuiltForm(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The quilt blob holds all patches, so its ID depends on all of them
+	var quilt bytes.Buffer
+	for _, p := range patches {
+		fmt.Fprintf(&quilt, "%s\n%d\n", p.identifier, len(p.data))
+		quilt.Write(p.data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := s.storeBlob(quilt.Bytes(), epochs, deletable)
+	quiltID := BlobID(quilt.Bytes())
+	s.quilts[quiltID] = patches
+
+	stored := make([]map[string]string, len(patches))
+	for i, p := range patches {
+		stored[i] = map[string]string{
+			"identifier":   p.identifier,
+			"quiltPatchId": QuiltPatchID(quiltID, i),
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"blobStoreResult":  result,
+		"storedQuiltBlobs": stored,
+	})
+}
+
+// parseQuiltForm parses the multipart body of a quilt upload. Each file field is a patch named
+// by its field name, and the _metadata field lists the tags of the patches.
+func parseQuiltForm(contentType string, body []byte) ([]quiltPatch, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, fmt.Errorf("expected multipart/form-data, got %q", contentType)
+	}
+
+	var patches []quiltPatch
+	var metadata []struct {
+		Identifier string            `json:"identifier"`
+		Tags       map[string]string `json:"tags"`
+	}
+	seen := make(map[string]bool)
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart body: %v", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart body: %v", err)
+		}
+
+		name := part.FormName()
+		if name == quiltMetadataField {
+			if err := json.Unmarshal(data, &metadata); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", quiltMetadataField, err)
+			}
+			continue
+		}
+		if name == "" {
+			return nil, fmt.Errorf("quilt patch without identifier")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate quilt patch identifier %q", name)
+		}
+		seen[name] = true
+		patches = append(patches, quiltPatch{identifier: name, data: data})
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("quilt has no patches")
+	}
+	sort.Slice(patches, func(i, j int) bool { return patches[i].identifier < patches[j].identifier })
+
+	for _, m := range metadata {
+		i := sort.Search(len(patches), func(i int) bool { return patches[i].identifier >= m.Identifier })
+		if i == len(patches) || patches[i].identifier != m.Identifier {
+			return nil, fmt.Errorf("metadata for unknown quilt patch %q", m.Identifier)
+		}
+		patches[i].tags = m.Tags
+	}
+	return patches, nil
+}
+
+func (s *Server) serveQuiltPatchByID(w http.ResponseWriter, r *http.Request, patchID string) {
+	quiltID, index, ok := parseQuiltPatchID(patchID)
+	if !ok {
+		http.Error(w, "invalid quilt patch ID", http.StatusBadRequest)
+		return
+	}
+	s.serveQuiltPatchAt(w, r, quiltID, func(patches []quiltPatch) int {
+		if index >= len(patches) {
+			return -1
+		}
+		return index
+	})
+}
+
+func (s *Server) serveQuiltPatch(w http.ResponseWriter, r *http.Request, path string) {
+	quiltID, identifier, ok := strings.Cut(path, "/")
+	if !ok || identifier == "" {
+		http.Error(w, "expected a quilt ID and an identifier", http.StatusBadRequest)
+		return
+	}
+	s.serveQuiltPatchAt(w, r, quiltID, func(patches []quiltPatch) int {
+		for i, p := range patches {
+			if p.identifier == identifier {
+				return i
+			}
+		}
+		return -1
+	})
+}
+
+// serveQuiltPatchAt serves the patch of a quilt selected by find, which returns -1 if there is none
+func (s *Server) serveQuiltPatchAt(w http.ResponseWriter, r *http.Request, quiltID string, find func([]quiltPatch) int) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	patches := s.quilts[quiltID]
+	b, stored := s.blobs[quiltID]
+	s.mu.Unlock()
+
+	index := find(patches)
+	if !stored || index < 0 {
+		http.Error(w, "quilt patch not found", http.StatusNotFound)
+		return
+	}
+
+	p := patches[index]
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("ETag", strconv.Quote(QuiltPatchID(quiltID, index)))
+	http.ServeContent(w, r, "", b.modTime, bytes.NewReader(p.data))
+}
@@ -0,0 +1,556 @@
+// Package walrustest provides an in-process fake Walrus publisher and aggregator for tests.
+//
+// The fake serves the HTTP API used by the walrus-go client from memory:
+//
+//	server := walrustest.NewServer()
+//	defer server.Close()
+//
+//	client := walrus.NewClient(
+//	    walrus.WithAggregatorURLs([]string{server.URL}),
+//	    walrus.WithPublisherURLs([]string{server.URL}),
+//	)
+//
+// Blob IDs are derived from the content, so storing the same data twice yields the same ID,
+// and faults such as latency, error responses and truncated bodies can be injected to test
+// retry and failover behavior.
+package walrustest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the API version reported by the fake's /v1/api endpoint
+const Version = "walrustest"
+
+// Server is a fake Walrus publisher and aggregator. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	blobs       map[string]*storedBlob
+	quilts      map[string][]quiltPatch
+	epoch       int
+	maxBlobSize int64
+	objects     int
+	faults      []*Fault
+	requests    []Request
+}
+
+// storedBlob is a blob held by the fake
+type storedBlob struct {
+	data      []byte
+	objectID  string
+	endEpoch  int
+	deletable bool
+	modTime   time.Time
+	// attributes are returned as response headers by blob reads
+	attributes map[string]string
+}
+
+// quiltPatch is a file stored in a quilt
+type quiltPatch struct {
+	identifier string
+	data       []byte
+	tags       map[string]string
+}
+
+// Request is a request received by the fake
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+}
+
+// Fault makes the fake misbehave for matching requests
+type Fault struct {
+	// Method and Path restrict the fault to requests with this method and path prefix;
+	// empty values match all requests
+	Method string
+	Path   string
+
+	// Latency delays the response
+	Latency time.Duration
+	// StatusCode, if set, is returned instead of handling the request
+	StatusCode int
+	// Body is the response body sent with StatusCode
+	Body string
+	// Header is added to the response, e.g. a Retry-After header for a 429 StatusCode
+	Header http.Header
+	// TruncateAfter, if positive, aborts the response after this many body bytes
+	TruncateAfter int
+
+	// Times limits how many requests the fault applies to; zero means all of them
+	Times int
+
+	hits int
+}
+
+// NewServer starts a fake at epoch 1
+func NewServer() *Server {
+	s := &Server{
+		blobs:  make(map[string]*storedBlob),
+		quilts: make(map[string][]quiltPatch),
+		epoch:  1,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BlobID returns the ID the fake assigns to data: the unpadded URL-safe base64 encoding of
+// its SHA-256 hash. Real Walrus blob IDs have the same shape but are computed differently.
+func BlobID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// BlobIDComputer computes the blob IDs assigned by the fake. It implements walrus.BlobIDComputer.
+type BlobIDComputer struct{}
+
+// ComputeBlobID returns the blob ID the fake assigns to the size bytes read from r
+func (BlobIDComputer) ComputeBlobID(r io.Reader, size int64) (string, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", fmt.Errorf("expected %d bytes, read %d", size, n)
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// PutBlob stores data directly, as if it had been stored for the given number of epochs,
+// and returns its blob ID
+func (s *Server) PutBlob(data []byte, epochs int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := BlobID(data)
+	s.blobs[id] = s.newBlob(id, data, epochs, false)
+	return id
+}
+
+// Blob returns the content of a stored blob
+func (s *Server) Blob(blobID string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.blobs[blobID]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), b.data...), true
+}
+
+// DeleteBlob removes a blob, so that reads return 404
+func (s *Server) DeleteBlob(blobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, blobID)
+}
+
+// ObjectID returns the Sui object ID of a stored blob
+func (s *Server) ObjectID(blobID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.blobs[blobID]
+	if !ok {
+		return "", false
+	}
+	return b.objectID, true
+}
+
+// SetAttributes sets the attributes of a stored blob, which reads return as response headers.
+// It reports whether the blob exists.
+func (s *Server) SetAttributes(blobID string, attributes map[string]string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.blobs[blobID]
+	if !ok {
+		return false
+	}
+	s.updateAttributes(blobID, b, attributes, true)
+	return true
+}
+
+// SetBlobAttributes adds attributes to the blob with the given object ID, replacing the values
+// of existing keys. It implements walrus.AttributeSetter, standing in for the Sui transaction
+// that sets attributes on a real blob object.
+func (s *Server) SetBlobAttributes(ctx context.Context, objectID string, attributes map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, b := range s.blobs {
+		if b.objectID == objectID {
+			s.updateAttributes(id, b, attributes, false)
+			return nil
+		}
+	}
+	return fmt.Errorf("blob object %s not found", objectID)
+}
+
+// updateAttributes replaces or extends the attributes of a blob. Blobs are replaced rather than
+// modified, since reads use them without holding s.mu. s.mu must be held.
+func (s *Server) updateAttributes(blobID string, b *storedBlob, attributes map[string]string, replace bool) {
+	updated := *b
+	updated.attributes = make(map[string]string, len(attributes))
+	if !replace {
+		for key, value := range b.attributes {
+			updated.attributes[key] = value
+		}
+	}
+	for key, value := range attributes {
+		updated.attributes[key] = value
+	}
+	s.blobs[blobID] = &updated
+}
+
+// SetEpoch sets the current epoch, which determines the end epoch of stored blobs
+func (s *Server) SetEpoch(epoch int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.epoch = epoch
+}
+
+// SetMaxBlobSize makes uploads larger than size fail with 413; zero removes the limit
+func (s *Server) SetMaxBlobSize(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxBlobSize = size
+}
+
+// InjectFault adds a fault. Faults are checked in the order they were added and the first
+// matching one applies.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+	})
+	fault := s.matchFault(r)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		for key, values := range fault.Header {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		if fault.StatusCode != 0 {
+			http.Error(w, fault.Body, fault.StatusCode)
+			return
+		}
+		if fault.TruncateAfter > 0 {
+			tw := &truncatingWriter{ResponseWriter: w, remaining: fault.TruncateAfter}
+			defer tw.abort()
+			w = tw
+		}
+	}
+
+	switch {
+	case r.URL.Path == "/v1/api":
+		s.serveAPISpec(w, r)
+	case r.URL.Path == "/v1/blobs":
+		s.serveStore(w, r)
+	case r.URL.Path == "/v1/quilts":
+		s.serveStoreQuilt(w, r)
+	case strings.HasPrefix(r.URL.Path, "/v1/blobs/by-quilt-patch-id/"):
+		s.serveQuiltPatchByID(w, r, strings.TrimPrefix(r.URL.Path, "/v1/blobs/by-quilt-patch-id/"))
+	case strings.HasPrefix(r.URL.Path, "/v1/blobs/by-quilt-id/"):
+		s.serveQuiltPatch(w, r, strings.TrimPrefix(r.URL.Path, "/v1/blobs/by-quilt-id/"))
+	case strings.HasPrefix(r.URL.Path, "/v1/blobs/by-object-id/"):
+		s.serveBlobByObjectID(w, r, strings.TrimPrefix(r.URL.Path, "/v1/blobs/by-object-id/"))
+	case strings.HasPrefix(r.URL.Path, "/v1/blobs/"):
+		s.serveBlob(w, r, strings.TrimPrefix(r.URL.Path, "/v1/blobs/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// matchFault returns the first fault matching r and counts the hit. s.mu must be held.
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for _, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if f.Path != "" && !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		if f.Times > 0 && f.hits >= f.Times {
+			continue
+		}
+		f.hits++
+		copied := *f
+		return &copied
+	}
+	return nil
+}
+
+func (s *Server) serveAPISpec(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":   "Walrus fake publisher and aggregator",
+			"version": Version,
+		},
+	})
+}
+
+func (s *Server) serveStore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	epochs, deletable, ok := storeParams(w, r)
+	if !ok {
+		return
+	}
+
+	data, ok := s.readUpload(w, r.Body)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.storeBlob(data, epochs, deletable))
+}
+
+// storeParams parses the query parameters of a store request, replying with an error if invalid
+func storeParams(w http.ResponseWriter, r *http.Request) (epochs int, deletable bool, ok bool) {
+	epochs = 1
+	if value := r.URL.Query().Get("epochs"); value != "" {
+		var err error
+		if epochs, err = strconv.Atoi(value); err != nil || epochs <= 0 {
+			http.Error(w, "invalid epochs", http.StatusBadRequest)
+			return 0, false, false
+		}
+	}
+	return epochs, r.URL.Query().Get("deletable") == "true", true
+}
+
+// readUpload reads an upload body, replying with an error if it can't be read or is too large
+func (s *Server) readUpload(w http.ResponseWriter, r io.Reader) ([]byte, bool) {
+	s.mu.Lock()
+	maxSize := s.maxBlobSize
+	s.mu.Unlock()
+
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return nil, false
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		http.Error(w, "blob is too large", http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	return data, true
+}
+
+// storeBlob stores data and returns the store response. s.mu must be held.
+func (s *Server) storeBlob(data []byte, epochs int, deletable bool) map[string]interface{} {
+	id := BlobID(data)
+	endEpoch := s.epoch + epochs
+
+	// A permanent blob that is already stored long enough is not stored again
+	if existing, ok := s.blobs[id]; ok && !existing.deletable && !deletable && existing.endEpoch >= endEpoch {
+		return map[string]interface{}{
+			"alreadyCertified": map[string]interface{}{
+				"blobId": id,
+				"event": map[string]string{
+					"txDigest": fakeDigest("tx", id),
+					"eventSeq": "0",
+				},
+				"endEpoch": existing.endEpoch,
+			},
+		}
+	}
+
+	b := s.newBlob(id, data, epochs, deletable)
+	s.blobs[id] = b
+	return map[string]interface{}{
+		"newlyCreated": map[string]interface{}{
+			"blobObject": map[string]interface{}{
+				"id":              b.objectID,
+				"storedEpoch":     s.epoch,
+				"registeredEpoch": s.epoch,
+				"blobId":          id,
+				"size":            len(data),
+				"erasureCodeType": "RedStuff",
+				"certifiedEpoch":  s.epoch,
+				"deletable":       deletable,
+				"storage": map[string]interface{}{
+					"id":          fakeDigest("storage", b.objectID),
+					"startEpoch":  s.epoch,
+					"endEpoch":    b.endEpoch,
+					"storageSize": encodedSize(len(data)),
+				},
+			},
+			"encodedSize": encodedSize(len(data)),
+			"cost":        encodedSize(len(data)) * epochs,
+		},
+	}
+}
+
+func (s *Server) serveBlob(w http.ResponseWriter, r *http.Request, blobID string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	b, ok := s.blobs[blobID]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "blob not found", http.StatusNotFound)
+		return
+	}
+	serveStoredBlob(w, r, blobID, b)
+}
+
+func (s *Server) serveBlobByObjectID(w http.ResponseWriter, r *http.Request, objectID string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	var blobID string
+	var found *storedBlob
+	for id, b := range s.blobs {
+		if b.objectID == objectID {
+			blobID, found = id, b
+			break
+		}
+	}
+	s.mu.Unlock()
+	if found == nil {
+		http.Error(w, "blob object not found", http.StatusNotFound)
+		return
+	}
+	serveStoredBlob(w, r, blobID, found)
+}
+
+// serveStoredBlob serves the content of a blob with its attributes as headers
+func serveStoredBlob(w http.ResponseWriter, r *http.Request, blobID string, b *storedBlob) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("ETag", strconv.Quote(blobID))
+	for key, value := range b.attributes {
+		w.Header().Set(key, value)
+	}
+	// ServeContent handles HEAD requests and Range headers
+	http.ServeContent(w, r, "", b.modTime, bytes.NewReader(b.data))
+}
+
+// newBlob creates a blob record with a fresh object ID. s.mu must be held.
+func (s *Server) newBlob(id string, data []byte, epochs int, deletable bool) *storedBlob {
+	s.objects++
+	return &storedBlob{
+		data:      append([]byte(nil), data...),
+		objectID:  fakeDigest("object", fmt.Sprintf("%s/%d", id, s.objects)),
+		endEpoch:  s.epoch + epochs,
+		deletable: deletable,
+		modTime:   time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// encodedSize approximates the size of a blob after erasure coding
+func encodedSize(size int) int {
+	return 5*size + 64*1024
+}
+
+// fakeDigest derives a deterministic Sui-style hex identifier
+func fakeDigest(kind, value string) string {
+	sum := sha256.Sum256([]byte(kind + ":" + value))
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// truncatingWriter passes through the first bytes of a response body and then aborts the
+// connection, so the client sees a body shorter than its Content-Length
+type truncatingWriter struct {
+	http.ResponseWriter
+	remaining int
+	truncated bool
+}
+
+func (w *truncatingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.remaining {
+		w.truncated = true
+		n, _ := w.ResponseWriter.Write(p[:w.remaining])
+		w.remaining -= n
+		return n, io.ErrShortWrite
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.remaining -= n
+	return n, err
+}
+
+// abort closes the connection if the body was truncated
+func (w *truncatingWriter) abort() {
+	if w.truncated {
+		if f, ok := w.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+		panic(http.ErrAbortHandler)
+	}
+}
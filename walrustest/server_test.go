@@ -0,0 +1,166 @@
+package walrustest_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	walrus "github.com/namihq/walrus-go"
+	"github.com/namihq/walrus-go/walrustest"
+)
+
+func newClient(server *walrustest.Server, opts ...walrus.ClientOption) *walrus.Client {
+	opts = append([]walrus.ClientOption{
+		walrus.WithAggregatorURLs([]string{server.URL}),
+		walrus.WithPublisherURLs([]string{server.URL}),
+		walrus.WithRetryConfig(2, time.Millisecond),
+	}, opts...)
+	return walrus.NewClient(opts...)
+}
+
+func TestStoreAndRead(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	server.SetEpoch(10)
+	client := newClient(server)
+
+	data := []byte("hello walrus")
+	resp, err := client.Store(data, &walrus.StoreOptions{Epochs: 5})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if resp.NewlyCreated == nil || resp.Blob.BlobID != walrustest.BlobID(data) || resp.Blob.EndEpoch != 15 {
+		t.Fatalf("Unexpected store response: %+v", resp)
+	}
+	if resp.NewlyCreated.BlobObject.Size != int64(len(data)) {
+		t.Errorf("Expected size %d, got %d", len(data), resp.NewlyCreated.BlobObject.Size)
+	}
+
+	// Storing the same content again for a shorter period is already certified
+	resp, err = client.Store(data, &walrus.StoreOptions{Epochs: 2})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if resp.AlreadyCertified == nil || resp.Blob.BlobID != walrustest.BlobID(data) || resp.Blob.EndEpoch != 15 {
+		t.Errorf("Expected an alreadyCertified response, got %+v", resp)
+	}
+
+	// Deletable blobs are always created anew
+	resp, err = client.Store(data, &walrus.StoreOptions{Epochs: 2, Deletable: true})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if resp.NewlyCreated == nil {
+		t.Errorf("Expected a newlyCreated response for a deletable blob, got %+v", resp)
+	}
+
+	read, err := client.Read(resp.Blob.BlobID, nil)
+	if err != nil || !bytes.Equal(read, data) {
+		t.Errorf("Read returned %q, %v", read, err)
+	}
+
+	metadata, err := client.Head(resp.Blob.BlobID)
+	if err != nil {
+		t.Fatalf("Head failed: %v", err)
+	}
+	if metadata.ContentLength != int64(len(data)) || metadata.ContentType != "application/octet-stream" || metadata.ETag == "" {
+		t.Errorf("Unexpected metadata: %+v", metadata)
+	}
+
+	part, err := client.ReadRange(resp.Blob.BlobID, 6, 3, nil)
+	if err != nil || string(part) != "wal" {
+		t.Errorf("ReadRange returned %q, %v", part, err)
+	}
+
+	spec, err := client.GetAPISpec(false)
+	if err != nil || !bytes.Contains(spec, []byte(walrustest.Version)) {
+		t.Errorf("GetAPISpec returned %s, %v", spec, err)
+	}
+
+	requests := server.Requests()
+	if len(requests) == 0 || requests[0].Method != http.MethodPut || requests[0].Query.Get("epochs") != "5" {
+		t.Errorf("Unexpected recorded requests: %+v", requests)
+	}
+}
+
+func TestNotFoundAndTooLarge(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := newClient(server)
+
+	if _, err := client.Read("missing", nil); !errors.Is(err, walrus.ErrBlobNotFound) {
+		t.Errorf("Expected ErrBlobNotFound, got: %v", err)
+	}
+
+	id := server.PutBlob([]byte("seeded"), 1)
+	if data, ok := server.Blob(id); !ok || string(data) != "seeded" {
+		t.Errorf("Blob returned %q, %v", data, ok)
+	}
+	server.DeleteBlob(id)
+	if _, err := client.Read(id, nil); !errors.Is(err, walrus.ErrBlobNotFound) {
+		t.Errorf("Expected ErrBlobNotFound after delete, got: %v", err)
+	}
+
+	server.SetMaxBlobSize(4)
+	if _, err := client.Store([]byte("too large"), nil); !errors.Is(err, walrus.ErrPayloadTooLarge) {
+		t.Errorf("Expected ErrPayloadTooLarge, got: %v", err)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	server := walrustest.NewServer()
+	defer server.Close()
+	client := newClient(server)
+	id := server.PutBlob([]byte("resilient content"), 1)
+
+	// Transient server errors are retried
+	server.InjectFault(walrustest.Fault{Method: http.MethodGet, StatusCode: http.StatusServiceUnavailable, Times: 2})
+	data, err := client.Read(id, nil)
+	if err != nil || string(data) != "resilient content" {
+		t.Errorf("Expected the read to recover, got %q, %v", data, err)
+	}
+
+	// A truncated body is reported as an error
+	server.ClearFaults()
+	server.InjectFault(walrustest.Fault{Path: "/v1/blobs/", TruncateAfter: 5})
+	reader, err := client.ReadToReader(id, nil)
+	if err != nil {
+		t.Fatalf("ReadToReader failed: %v", err)
+	}
+	data, err = io.ReadAll(reader)
+	reader.Close()
+	if err == nil || string(data) != "resil" {
+		t.Errorf("Expected a truncated read, got %q, %v", data, err)
+	}
+
+	// Latency is applied before the response
+	server.ClearFaults()
+	server.InjectFault(walrustest.Fault{Latency: 50 * time.Millisecond, Times: 1})
+	start := time.Now()
+	if _, err := client.Read(id, nil); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected at least 50ms latency, took %v", elapsed)
+	}
+
+	// Faults can carry headers, such as Retry-After
+	server.InjectFault(walrustest.Fault{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"1"}},
+		Times:      1,
+	})
+	var retries []walrus.RetryEvent
+	client = newClient(server, walrus.WithRetryHook(func(e walrus.RetryEvent) {
+		retries = append(retries, e)
+	}))
+	if _, err := client.Read(id, nil); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(retries) != 1 || retries[0].Delay != time.Second {
+		t.Errorf("Expected one retry honoring Retry-After, got %+v", retries)
+	}
+}